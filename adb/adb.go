@@ -0,0 +1,93 @@
+// Package adb provides executor backend running commands on Android devices with adb shell
+package adb
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/SCP002/executor"
+)
+
+// Backend runs commands on Android device with "adb -s <serial> shell".
+//
+// Exit codes and separate StdOut and StdErr require shell protocol v2 (Android 7 and later).
+// On older devices a warning is printed and exit code is always 0, while StdErr is merged into StdOut.
+// Options.Dir is the working directory on the device, other options apply to the local adb process.
+// The global policy is consulted about both the command on the device and the local adb
+type Backend struct {
+	ADB    string // Path to adb executable ("adb" from PATH if not set)
+	Serial string // Serial number of device, see "adb devices" (the only connected device if not set)
+
+	once    sync.Once
+	shellV2 bool
+}
+
+// New returns backend running commands on device with <serial>
+func New(serial string) *Backend {
+	return &Backend{Serial: serial}
+}
+
+// Start implements executor.Backend
+func (b *Backend) Start(opts executor.Options) executor.Result {
+	b.once.Do(func() {
+		b.shellV2 = b.hasShellV2()
+		if !b.shellV2 {
+			executor.Diagln("adb shell protocol v2 is not supported by device, exit codes are not available")
+		}
+	})
+
+	if err := executor.CheckRemotePolicy(opts.Command, opts.Args, opts.Dir); err != nil {
+		executor.Diagln(err)
+		return executor.Result{ExitCode: -1, Err: err}
+	}
+	opts.Args = append(b.adbArgs(), "shell", remoteCommand(opts.Command, opts.Args, opts.Dir))
+	opts.Command = b.adb()
+	opts.Dir = ""
+	// OnExit is called by executor.Start of the caller
+	opts.OnExit = nil
+	return executor.Start(opts)
+}
+
+// adb returns path to adb executable
+func (b *Backend) adb() string {
+	if b.ADB == "" {
+		return "adb"
+	}
+	return b.ADB
+}
+
+// adbArgs returns global adb arguments selecting the device
+func (b *Backend) adbArgs() []string {
+	if b.Serial == "" {
+		return nil
+	}
+	return []string{"-s", b.Serial}
+}
+
+// hasShellV2 returns true if both adb and device support shell protocol v2
+func (b *Backend) hasShellV2() bool {
+	out, err := exec.Command(b.adb(), append(b.adbArgs(), "features")...).Output()
+	if err != nil {
+		return false
+	}
+	for _, feature := range strings.FieldsFunc(string(out), func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if strings.TrimSpace(feature) == "shell_v2" {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteCommand returns command line for device shell running <command> with <args> in <dir>
+func remoteCommand(command string, args []string, dir string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{command}, args...) {
+		parts = append(parts, executor.QuotePosix(arg))
+	}
+	line := strings.Join(parts, " ")
+	if dir != "" {
+		line = "cd " + executor.QuotePosix(dir) + " && " + line
+	}
+	return line
+}
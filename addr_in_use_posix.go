@@ -0,0 +1,13 @@
+// +build !windows
+
+package executor
+
+import (
+	"errors"
+	"syscall"
+)
+
+// addrInUse returns true if <err> means address is already taken by another socket
+func addrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
@@ -0,0 +1,14 @@
+// +build windows
+
+package executor
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// addrInUse returns true if <err> means address is already taken by another socket
+func addrInUse(err error) bool {
+	return errors.Is(err, windows.WSAEADDRINUSE)
+}
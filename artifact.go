@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Artifact represents a file produced by the process
+type Artifact struct {
+	Path   string // Path to file
+	Size   int64  // Size of file in bytes
+	SHA256 string // Hex encoded SHA-256 hash of file content
+}
+
+// collectArtifacts enumerates files matching glob <patterns> relative to <dir>
+func collectArtifacts(dir string, patterns []string) ([]Artifact, error) {
+	var artifacts []Artifact
+	seen := map[string]bool{}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			hash, err := hashFile(match)
+			if err != nil {
+				return nil, err
+			}
+			artifacts = append(artifacts, Artifact{
+				Path:   match,
+				Size:   info.Size(),
+				SHA256: hash,
+			})
+		}
+	}
+
+	return artifacts, nil
+}
+
+// hashFile returns hex encoded SHA-256 hash of file at <path>
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
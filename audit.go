@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// AuditEventType represents type of audit event
+type AuditEventType string
+
+const (
+	AuditStart  AuditEventType = "start"  // Process started
+	AuditFinish AuditEventType = "finish" // Process finished or failed to start
+)
+
+// AuditEvent represents start or finish of a process
+type AuditEvent struct {
+	Type     AuditEventType // Event type
+	Time     time.Time      // Time of event
+	Trace    ExecTrace      // Record of what was executed
+	StartOk  bool           // Process started successfully?
+	DoneOk   bool           // Process exited successfully? Only set for finish events
+	ExitCode int            // Exit code. Only set for finish events
+}
+
+// Auditor receives start and finish events of every process started with Start, Exec, Daemonize, Self and Handoff.
+// Finish events are only sent for processes started with Options.Wait, or failed to start
+type Auditor interface {
+	Audit(e AuditEvent) error
+}
+
+var (
+	auditorMu sync.RWMutex
+	auditor   Auditor
+)
+
+// SetAuditor sets global auditor receiving events of all processes. Nil disables auditing
+func SetAuditor(a Auditor) {
+	auditorMu.Lock()
+	defer auditorMu.Unlock()
+	auditor = a
+}
+
+// audit sends event of <eventType> for <res> and process <state> (if finished) to the global auditor, if set
+func audit(eventType AuditEventType, res Result, state *os.ProcessState) {
+	auditorMu.RLock()
+	a := auditor
+	auditorMu.RUnlock()
+	if a == nil {
+		return
+	}
+
+	e := AuditEvent{
+		Type:    eventType,
+		Time:    time.Now(),
+		Trace:   res.Trace,
+		StartOk: res.StartOk,
+	}
+	if eventType == AuditFinish {
+		e.ExitCode = -1
+		if state != nil {
+			e.DoneOk = state.Success()
+			e.ExitCode = state.ExitCode()
+		}
+	}
+	if err := a.Audit(e); err != nil {
+		diagln(err)
+	}
+}
+
+// startAudited starts <cmd> with <start> and sends its events to the global auditor, like Start does, for processes
+// started outside of it. Values of <redact> variables are hidden in the trace. Returns result to audit finish with
+func startAudited(cmd *exec.Cmd, redact []string, start func() error) (Result, error) {
+	res := Result{ExitCode: -1, Trace: newExecTrace(cmd, redact)}
+	debugTrace(res.Trace)
+	if err := start(); err != nil {
+		audit(AuditFinish, res, nil)
+		return res, err
+	}
+	res.StartOk = true
+	if cmd.Process != nil {
+		res.PID = cmd.Process.Pid
+	}
+	audit(AuditStart, res, nil)
+	return res, nil
+}
+
+// FileAuditor appends events to a file as JSON lines
+type FileAuditor struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditor returns auditor appending events to file at <path>
+func NewFileAuditor(path string) (*FileAuditor, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditor{file: file}, nil
+}
+
+// Audit implements Auditor
+func (a *FileAuditor) Audit(e AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the file
+func (a *FileAuditor) Close() error {
+	return a.file.Close()
+}
+
+// WebhookAuditor sends events as JSON in POST requests
+type WebhookAuditor struct {
+	URL    string       // URL to send events to
+	Client *http.Client // HTTP client to use (client with 10 seconds timeout if nil)
+}
+
+// Audit implements Auditor
+func (a *WebhookAuditor) Audit(e AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Post(a.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook responded with %v", resp.Status)
+	}
+	return nil
+}
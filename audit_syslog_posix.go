@@ -0,0 +1,36 @@
+// +build !windows
+
+package executor
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogAuditor sends events to syslog as JSON messages
+type SyslogAuditor struct {
+	w *syslog.Writer
+}
+
+// NewSyslogAuditor returns auditor sending events to local syslog with <tag>
+func NewSyslogAuditor(tag string) (*SyslogAuditor, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogAuditor{w: w}, nil
+}
+
+// Audit implements Auditor
+func (a *SyslogAuditor) Audit(e AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return a.w.Info(string(data))
+}
+
+// Close closes connection to syslog
+func (a *SyslogAuditor) Close() error {
+	return a.w.Close()
+}
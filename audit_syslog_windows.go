@@ -0,0 +1,25 @@
+// +build windows
+
+package executor
+
+import (
+	"errors"
+)
+
+// SyslogAuditor sends events to syslog as JSON messages
+type SyslogAuditor struct{}
+
+// NewSyslogAuditor returns error as syslog is not available on Windows
+func NewSyslogAuditor(tag string) (*SyslogAuditor, error) {
+	return nil, errors.New("syslog is not available on Windows")
+}
+
+// Audit implements Auditor
+func (a *SyslogAuditor) Audit(e AuditEvent) error {
+	return errors.New("syslog is not available on Windows")
+}
+
+// Close closes connection to syslog
+func (a *SyslogAuditor) Close() error {
+	return nil
+}
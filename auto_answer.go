@@ -0,0 +1,191 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPromptLength is a maximum length of unterminated output line kept for matching prompts
+const maxPromptLength = 4096
+
+// promptIdleDelay is a time output has to stay idle for unterminated line to be matched as a prompt
+const promptIdleDelay = 100 * time.Millisecond
+
+// PasswordPrompt matches common password and passphrase prompts
+var PasswordPrompt = regexp.MustCompile(`(?i)\b(password|passphrase|passcode|pin)\b[^:\n]*:\s*$`)
+
+// AnswerRule represents an automatic answer to a prompt printed by process
+type AnswerRule struct {
+	Pattern  *regexp.Regexp     // Prompt to match against finished or idle output line, e.g. `Continue\? \[y/N\]`
+	Response string             // Text to write to StdIn, including line break if process expects one
+	Secret   CredentialProvider // Provider of secret to write to StdIn followed by line break, instead of Response
+	Times    int                // Maximum number of times to answer (0 = unlimited)
+}
+
+// CredentialProvider provides secrets to answer prompts with.
+// Secrets are written to StdIn only: they are never printed, captured or passed to callbacks
+type CredentialProvider interface {
+	Credential(prompt string) ([]byte, error) // Returns secret for <prompt>. Returned slice is zeroed after use
+}
+
+// CredentialFunc is an adapter to use a function as CredentialProvider
+type CredentialFunc func(prompt string) ([]byte, error)
+
+// Credential implements CredentialProvider
+func (f CredentialFunc) Credential(prompt string) ([]byte, error) {
+	return f(prompt)
+}
+
+// answer represents a response queued to be written to StdIn
+type answer struct {
+	response string
+	secret   CredentialProvider
+	prompt   string
+}
+
+// autoAnswerer watches output lines and writes responses to StdIn when prompts appear
+type autoAnswerer struct {
+	rules   []AnswerRule
+	used    []int
+	answers chan answer
+	stopped bool
+}
+
+// newAutoAnswerer returns answerer writing responses of <rules> into <stdin>
+func newAutoAnswerer(rules []AnswerRule, stdin *stdinWriter) *autoAnswerer {
+	a := &autoAnswerer{
+		rules:   rules,
+		used:    make([]int, len(rules)),
+		answers: make(chan answer, 16),
+	}
+
+	// Write asynchronously, so output reading is never blocked by process not reading StdIn
+	// or credential provider waiting for user input
+	goTracked(func() {
+		for ans := range a.answers {
+			if err := ans.write(stdin); err != nil {
+				diagln(err)
+				return
+			}
+		}
+	})
+
+	return a
+}
+
+// check matches output line <pending> against rules and queues response of the first matching one.
+// Matched text is consumed from <pending>, so the same prompt is answered once. Response is dropped if the queue
+// is full. Must be called with output scanner locked
+func (a *autoAnswerer) check(pending *strings.Builder) {
+	if a.stopped || pending.Len() == 0 {
+		return
+	}
+	line := pending.String()
+	for i, rule := range a.rules {
+		if rule.Times > 0 && a.used[i] >= rule.Times {
+			continue
+		}
+		if !rule.Pattern.MatchString(line) {
+			continue
+		}
+		pending.Reset()
+		select {
+		case a.answers <- answer{response: rule.Response, secret: rule.Secret, prompt: line}:
+			a.used[i]++
+		default:
+			// Called with output scanner locked, so waiting for the writer would stop reading output of process,
+			// which may itself wait for its output to be read
+			diagf("answer to prompt %q is dropped, as previous answers are not written yet\n", line)
+		}
+		return
+	}
+}
+
+// stop stops writing responses. Must be called with output scanner locked. Safe to call on nil answerer
+func (a *autoAnswerer) stop() {
+	if a != nil && !a.stopped {
+		a.stopped = true
+		close(a.answers)
+	}
+}
+
+// promptLine is unterminated output line of a stream. It is matched against answer rules once it is finished,
+// or once output stays idle for promptIdleDelay, as process waits for input after printing a prompt. Matching
+// every character would answer a prompt-like beginning of a line which goes on
+type promptLine struct {
+	answerer  *autoAnswerer
+	mu        *sync.Mutex // Output scanner mutex
+	sb        strings.Builder
+	lastWrite time.Time
+	timer     *time.Timer
+}
+
+// newPromptLine returns line checked by <answerer> with output scanner mutex <mu> locked
+func newPromptLine(answerer *autoAnswerer, mu *sync.Mutex) *promptLine {
+	return &promptLine{answerer: answerer, mu: mu}
+}
+
+// write appends <char> to the line, or finishes the line if it is a line break. Must be called with output scanner
+// locked
+func (l *promptLine) write(char []byte) {
+	if isByte(char, '\n') || isByte(char, '\r') {
+		l.answerer.check(&l.sb)
+		l.sb.Reset()
+		return
+	}
+
+	l.sb.Write(char)
+	if l.sb.Len() > maxPromptLength {
+		tail := l.sb.String()[l.sb.Len()-maxPromptLength:]
+		l.sb.Reset()
+		l.sb.WriteString(tail)
+	}
+	l.lastWrite = time.Now()
+	if l.timer == nil {
+		l.timer = time.AfterFunc(promptIdleDelay, l.idle)
+	} else {
+		l.timer.Reset(promptIdleDelay)
+	}
+}
+
+// idle matches the line once output stayed idle
+func (l *promptLine) idle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Timer fired while more output was written and was reset already
+	if time.Since(l.lastWrite) < promptIdleDelay {
+		return
+	}
+	l.answerer.check(&l.sb)
+}
+
+// stop stops waiting for output to go idle
+func (l *promptLine) stop() {
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+}
+
+// write writes the response into <stdin>, requesting secret from provider if set
+func (ans answer) write(stdin *stdinWriter) error {
+	if ans.secret == nil {
+		return stdin.write([]byte(ans.response))
+	}
+
+	secret, err := ans.secret.Credential(ans.prompt)
+	if err != nil {
+		return fmt.Errorf("can not get secret for prompt %q: %v", ans.prompt, err)
+	}
+	data := append(secret, '\n')
+	err = stdin.write(data)
+	for i := range data {
+		data[i] = 0
+	}
+	for i := range secret {
+		secret[i] = 0
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writePrompt writes <text> into new prompt line checked against <rules> and returns answers queued after output
+// stayed idle
+func writePrompt(text string, rules ...AnswerRule) []answer {
+	var mu sync.Mutex
+	a := &autoAnswerer{rules: rules, used: make([]int, len(rules)), answers: make(chan answer, 16)}
+	line := newPromptLine(a, &mu)
+	for _, c := range text {
+		mu.Lock()
+		line.write([]byte(string(c)))
+		mu.Unlock()
+	}
+	time.Sleep(3 * promptIdleDelay)
+	mu.Lock()
+	line.stop()
+	a.stop()
+	mu.Unlock()
+
+	var answers []answer
+	for ans := range a.answers {
+		answers = append(answers, ans)
+	}
+	return answers
+}
+
+func TestAutoAnswerIdlePrompt(t *testing.T) {
+	rule := AnswerRule{Pattern: regexp.MustCompile(`Continue\? \[y/N\] $`), Response: "y\n"}
+	answers := writePrompt("Continue? [y/N] ", rule)
+	if len(answers) != 1 || answers[0].response != "y\n" {
+		t.Errorf("answers to idle prompt: %+v, want one", answers)
+	}
+}
+
+func TestAutoAnswerFinishedLine(t *testing.T) {
+	rule := AnswerRule{Pattern: regexp.MustCompile(`Continue\?\s*$`), Response: "y\n"}
+	if answers := writePrompt("Continue?\n", rule); len(answers) != 1 {
+		t.Errorf("answers to finished prompt line: %+v, want one", answers)
+	}
+}
+
+func TestAutoAnswerLineGoesOn(t *testing.T) {
+	rule := AnswerRule{Pattern: PasswordPrompt, Response: "secret\n"}
+	for _, text := range []string{"Password: changed successfully\n", "Password: changed successfully"} {
+		if answers := writePrompt(text, rule); len(answers) != 0 {
+			t.Errorf("answers to %q: %+v, want none", text, answers)
+		}
+	}
+}
+
+func TestAutoAnswerTimes(t *testing.T) {
+	rule := AnswerRule{Pattern: regexp.MustCompile(`\?\s*$`), Response: "y\n", Times: 1}
+	if answers := writePrompt("First?\nSecond?\n", rule); len(answers) != 1 {
+		t.Errorf("answers: %+v, want one", answers)
+	}
+}
+
+func TestPasswordPrompt(t *testing.T) {
+	prompts := []string{
+		"Password:",
+		"Password: ",
+		"[sudo] password for user: ",
+		"Enter passphrase for key '/home/user/.ssh/id_ed25519': ",
+		"Enter PIN: ",
+		"Passcode:",
+	}
+	for _, prompt := range prompts {
+		if !PasswordPrompt.MatchString(prompt) {
+			t.Errorf("%q is not matched", prompt)
+		}
+	}
+	lines := []string{
+		"Copying:",
+		"Mapping:",
+		"Shipping:",
+		"Spinning up: ",
+		"Passwords saved: ",
+		"Password: changed",
+	}
+	for _, line := range lines {
+		if PasswordPrompt.MatchString(line) {
+			t.Errorf("%q is matched", line)
+		}
+	}
+}
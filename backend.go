@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// LocalBackendName is the name of the backend running processes on the local machine
+const LocalBackendName = "local"
+
+// Backend represents a way to run processes, like on the local machine, over SSH or on a device.
+//
+// Start is called with Options.Backend and Options.Cache cleared and must follow semantics of the package Start.
+// Options the backend can't honor must not be ignored: Start must return result with Err wrapping ErrUnsupported
+// instead, as the package does for the local platform (see Capabilities).
+// Options.OnExit is called by the package Start once backend returned, so backends delegating to the package Start
+// must clear it to not call it twice.
+// Backends are not used for processes of Pipeline, which are connected with local pipes
+type Backend interface {
+	Start(opts Options) Result
+}
+
+// LocalBackend runs processes on the local machine
+type LocalBackend struct{}
+
+// Start implements Backend
+func (LocalBackend) Start(opts Options) Result {
+	return startLocal(opts)
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{LocalBackendName: LocalBackend{}}
+)
+
+// RegisterBackend makes backend <b> available as Options.Backend <name>. Returns error if the name is already taken
+func RegisterBackend(name string, b Backend) error {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, ok := backends[name]; ok {
+		return fmt.Errorf("backend %q is already registered", name)
+	}
+	backends[name] = b
+	return nil
+}
+
+// Backends returns sorted names of registered backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// startBackend starts process described by <opts> with backend set by Options.Backend
+func startBackend(opts Options) Result {
+	name := opts.Backend
+	if name == "" {
+		name = LocalBackendName
+	}
+	backendsMu.RLock()
+	b, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return startFailed(Result{ExitCode: -1}, fmt.Errorf("unknown backend %q", name))
+	}
+	opts.Backend = ""
+	return b.Start(opts)
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"sort"
+	"time"
+)
+
+// BenchOptions represents options of repeated runs for benchmarking
+type BenchOptions struct {
+	Runs       int  // Number of measured runs (10 if not set)
+	Warmup     int  // Number of runs before measuring, to warm up caches
+	DropCaches bool // Drop file system caches before each measured run (Linux only, requires root)?
+}
+
+// BenchResult represents timings of benchmark runs
+type BenchResult struct {
+	Min     time.Duration   // Shortest run
+	Median  time.Duration   // Median run
+	P95     time.Duration   // 95th percentile run
+	Max     time.Duration   // Longest run
+	Mean    time.Duration   // Average run
+	Times   []time.Duration // Durations of measured runs, in order of running
+	Results []Result        // Results of measured runs, in order of running
+}
+
+// Bench runs a process described by <opts> BenchOptions.Runs times after BenchOptions.Warmup runs and measures
+// wall clock time from start until exit of each run. Process is always waited for.
+//
+// Returns error if a run could not be started or caches could not be dropped. Runs which exited with non-zero code
+// are measured as usual, check BenchResult.Results
+func Bench(opts Options, bopts BenchOptions) (BenchResult, error) {
+	var br BenchResult
+	runs := bopts.Runs
+	if runs <= 0 {
+		runs = 10
+	}
+	opts.Wait = true
+
+	for i := 0; i < bopts.Warmup; i++ {
+		if res := Start(opts); !res.StartOk {
+			return br, res.Err
+		}
+	}
+
+	for i := 0; i < runs; i++ {
+		if bopts.DropCaches {
+			if err := dropCaches(); err != nil {
+				return br, err
+			}
+		}
+		start := time.Now()
+		res := Start(opts)
+		elapsed := time.Since(start)
+		if !res.StartOk {
+			return br, res.Err
+		}
+		br.Times = append(br.Times, elapsed)
+		br.Results = append(br.Results, res)
+	}
+
+	sorted := append([]time.Duration(nil), br.Times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, t := range sorted {
+		total += t
+	}
+	br.Min = sorted[0]
+	br.Max = sorted[len(sorted)-1]
+	br.Median = percentile(sorted, 50)
+	br.P95 = percentile(sorted, 95)
+	br.Mean = total / time.Duration(len(sorted))
+	return br, nil
+}
+
+// percentile returns <p>th percentile of non-empty ascending <sorted> durations, using nearest-rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,17 @@
+// +build !windows
+
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// brokenPipe returns true if process with <state> was killed by SIGPIPE, or exited with code 141 shells report
+// for it, after its consumer closed StdOut early
+func brokenPipe(state *os.ProcessState) bool {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() && ws.Signal() == syscall.SIGPIPE {
+		return true
+	}
+	return state.ExitCode() == 128+int(syscall.SIGPIPE)
+}
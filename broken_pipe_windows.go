@@ -0,0 +1,12 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+)
+
+// brokenPipe returns false, as there is no signal or exit code meaning process stopped writing into closed pipe
+func brokenPipe(_ *os.ProcessState) bool {
+	return false
+}
@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache represents cache of results of idempotent processes, keyed by hash of command, arguments, working directory,
+// environment, StdIn content and options shaping captured output. Only results of waited for, successfully finished
+// processes are cached.
+// Processes with inherited StdIn or reading Options.Stdin, which can't be a part of the key, and processes with
+// Options.Transforms are always started. Set Options.StdinSource to StdinNull for processes reading no input
+type Cache struct {
+	Store CacheStore    // Storage of cached results (in-memory if not set)
+	TTL   time.Duration // Time to keep results for (forever if not set)
+
+	once sync.Once
+}
+
+// CacheEntry represents cached result
+type CacheEntry struct {
+	Result Result    // Result of the process
+	Time   time.Time // Time the process finished
+}
+
+// CacheStore represents storage of cached results
+type CacheStore interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, e CacheEntry)
+}
+
+// MemoryCacheStore is an in-memory CacheStore, safe for concurrent use
+type MemoryCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// NewMemoryCacheStore returns empty MemoryCacheStore
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: map[string]CacheEntry{}}
+}
+
+// Get implements CacheStore
+func (s *MemoryCacheStore) Get(key string) (CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	return e, ok
+}
+
+// Set implements CacheStore
+func (s *MemoryCacheStore) Set(key string, e CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = e
+}
+
+// start returns cached result of process described by <opts> if it is not expired, otherwise starts the process
+// and caches its result
+func (c *Cache) start(opts Options) Result {
+	c.once.Do(func() {
+		if c.Store == nil {
+			c.Store = NewMemoryCacheStore()
+		}
+	})
+	opts.Cache = nil
+
+	// StdIn inherited or read from reader as is can't be a part of the key, neither can transform functions
+	stdinKnown := opts.StdinFunc != nil || len(opts.AutoAnswer) > 0 || (opts.StdinSource == StdinNull && opts.pipeIn == nil)
+	if !stdinKnown || len(opts.Transforms) > 0 {
+		return start(opts)
+	}
+	// Directory is chosen once, so the key and the process agree on it
+	if opts.DirFunc != nil {
+		opts.Dir = opts.DirFunc()
+		opts.DirFunc = nil
+	}
+
+	// StdIn is read upfront to be a part of the key, then fed from memory
+	var stdin []byte
+	if opts.StdinFunc != nil {
+		var err error
+		stdin, err = readStdinFunc(opts.StdinFunc)
+		if err != nil {
+			return startFailed(Result{ExitCode: -1}, err)
+		}
+		fed := false
+		opts.StdinFunc = func() ([]byte, error) {
+			if fed {
+				return nil, io.EOF
+			}
+			fed = true
+			return stdin, nil
+		}
+	}
+
+	key, err := cacheKey(opts, stdin)
+	if err != nil {
+		return startFailed(Result{ExitCode: -1}, err)
+	}
+	if e, ok := c.Store.Get(key); ok && (c.TTL <= 0 || time.Since(e.Time) < c.TTL) {
+		return e.Result
+	}
+
+	res := start(opts)
+	if res.DoneOk {
+		c.Store.Set(key, CacheEntry{Result: res, Time: time.Now()})
+	}
+	return res
+}
+
+// readStdinFunc calls <f> until it returns error and returns concatenated data. io.EOF is not an error
+func readStdinFunc(f func() ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		data, err := f()
+		buf.Write(data)
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// cacheKey returns hex encoded hash of backend, command, arguments, working directory, environment and options shaping
+// captured output of <opts> and <stdin>
+func cacheKey(opts Options, stdin []byte) (string, error) {
+	env, err := processEnv(opts)
+	if err != nil {
+		return "", err
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append([]string(nil), env...)
+	sort.Strings(env)
+
+	h := sha256.New()
+	// Length prefixes keep boundaries between parts unambiguous
+	write := func(s string) {
+		fmt.Fprintf(h, "%v:%v", len(s), s)
+	}
+	write(opts.Backend)
+	write(opts.Command)
+	write(fmt.Sprint(len(opts.Args)))
+	for _, arg := range opts.Args {
+		write(arg)
+	}
+	write(opts.Dir)
+	write(fmt.Sprint(len(env)))
+	for _, kv := range env {
+		write(kv)
+	}
+	write(string(stdin))
+	write(fmt.Sprint(opts.Capture, opts.SeparateCapture, opts.NormalizeNewlines, opts.InvalidBytes, opts.CaptureMemoryLimit))
+	write(fmt.Sprint(opts.Encoding, opts.StdoutEncoding, opts.StderrEncoding, opts.StdinEncoding))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// CapabilityReport represents features of the package available on the current platform
+type CapabilityReport struct {
+	OS               string // Operating system, like runtime.GOOS
+	NetworkIsolation bool   // Network namespaces for Options.NoNetwork (Linux)
+	RestrictedToken  bool   // Restricted tokens for Options.RestrictToken (Windows)
+	UserSessions     bool   // Running in session of logged on user for Options.UserSession (Windows)
+	CodeSignatures   bool   // Code signature verification for Options.RequireSignature (Windows and macOS)
+	IOPriority       bool   // I/O priority of Options.IOPriority (Linux and Windows)
+	JobObjects       bool   // Job objects enforcing Options.CPUTimeLimit (Windows), elsewhere RLIMIT_CPU or sampling is used
+	Consoles         bool   // Console windows of Options.NewConsole and Options.Hide (Windows), elsewhere they are ignored
+	BrokenPipeSignal bool   // SIGPIPE recognized by Options.IgnoreBrokenPipe (not Windows)
+	GracefulStop     bool   // Termination signal asking processes to exit in RunningPipeline.Stop (not Windows)
+	DropCaches       bool   // Dropping file system caches in BenchOptions.DropCaches (Linux)
+}
+
+// Capabilities returns features of the package available on the current platform
+func Capabilities() CapabilityReport {
+	goos := runtime.GOOS
+	windows := goos == "windows"
+	return CapabilityReport{
+		OS:               goos,
+		NetworkIsolation: goos == "linux",
+		RestrictedToken:  windows,
+		UserSessions:     windows,
+		CodeSignatures:   windows || goos == "darwin",
+		IOPriority:       windows || goos == "linux",
+		JobObjects:       windows,
+		Consoles:         windows,
+		BrokenPipeSignal: !windows,
+		GracefulStop:     !windows,
+		DropCaches:       goos == "linux",
+	}
+}
+
+// checkSupported returns ErrUnsupported naming the first option of <opts> which can't be honored on the current
+// platform. Backends other than the local one check options themselves, see Backend
+func checkSupported(opts Options) error {
+	c := Capabilities()
+	var option string
+	switch {
+	case opts.NoNetwork && !c.NetworkIsolation:
+		option = "NoNetwork"
+	case opts.RestrictToken && !c.RestrictedToken:
+		option = "RestrictToken"
+	case opts.UserSession && !c.UserSessions:
+		option = "UserSession"
+	case opts.RequireSignature && !c.CodeSignatures:
+		option = "RequireSignature"
+	case opts.IOPriority.Class != IOClassNone && !c.IOPriority:
+		option = "IOPriority"
+	case opts.IgnoreBrokenPipe && !c.BrokenPipeSignal:
+		option = "IgnoreBrokenPipe"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: Options.%v on %v", ErrUnsupported, option, c.OS)
+}
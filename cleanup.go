@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"os"
+	"sync"
+)
+
+// Processes started with Options.Cleanup, by ID
+var (
+	cleanupMu    sync.Mutex
+	cleanupProcs = map[int]*os.Process{}
+)
+
+// registerCleanup remembers <proc> to kill with Cleanup or CleanupAll, until it exits on its own
+func registerCleanup(proc *os.Process) {
+	cleanupMu.Lock()
+	cleanupProcs[proc.Pid] = proc
+	cleanupMu.Unlock()
+
+	exited := processExited(proc)
+	goTracked(func() {
+		<-exited
+		cleanupMu.Lock()
+		defer cleanupMu.Unlock()
+		// PID may be reused by another process registered since then
+		if cleanupProcs[proc.Pid] == proc {
+			delete(cleanupProcs, proc.Pid)
+		}
+	})
+}
+
+// Cleanup kills process with <pid> started with Options.Cleanup and its descendants, if it was not cleaned up yet
+// and did not exit
+func Cleanup(pid int) {
+	cleanupMu.Lock()
+	proc, ok := cleanupProcs[pid]
+	delete(cleanupProcs, pid)
+	cleanupMu.Unlock()
+	if ok {
+		killTree(proc)
+	}
+}
+
+// CleanupAll kills all processes started with Options.Cleanup and their descendants, to call before exiting,
+// like at the end of TestMain
+func CleanupAll() {
+	cleanupMu.Lock()
+	procs := cleanupProcs
+	cleanupProcs = map[int]*os.Process{}
+	cleanupMu.Unlock()
+	for _, proc := range procs {
+		killTree(proc)
+	}
+}
+
+// killTree kills <proc> and its descendants, then reaps <proc> so it does not remain a zombie
+func killTree(proc *os.Process) {
+	killWithDescendants(proc)
+	_, _ = proc.Wait()
+}
+
+// killWithDescendants kills <proc> and its descendants, which could keep its output open
+func killWithDescendants(proc *os.Process) {
+	// Descendants are found before their parent dies and they get reparented
+	descendants, err := Children(proc.Pid)
+	if err != nil {
+		diagln(err)
+	}
+	_ = proc.Kill()
+	for _, child := range flattenProcessTree(descendants) {
+		if p, err := os.FindProcess(child.PID); err == nil {
+			_ = p.Kill()
+			_ = p.Release()
+		}
+	}
+}
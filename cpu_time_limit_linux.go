@@ -0,0 +1,53 @@
+// +build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// cpuTimeLimiter represents RLIMIT_CPU of process
+type cpuTimeLimiter struct {
+	seconds uint
+}
+
+// cpuTimeLimitCommand returns command and arguments running <command> with <args> in <dir> with RLIMIT_CPU of
+// <seconds> (SIGXCPU, then SIGKILL a second later).
+//
+// The limit is set by shell replacing itself with the command, so it is in place before the command starts and its
+// children inherit it
+func cpuTimeLimitCommand(command string, args []string, dir string, seconds uint) (string, []string, error) {
+	path, err := resolveCommand(normalizeCommandPath(command), dir)
+	if err != nil {
+		return "", nil, err
+	}
+	script := fmt.Sprintf(`ulimit -S -t %v && ulimit -H -t %v && exec "$0" "$@"`, seconds, seconds+1)
+	return "/bin/sh", append([]string{"-c", script, path}, args...), nil
+}
+
+// limitCPUTime returns limiter of process <p>, which got RLIMIT_CPU of <seconds> with cpuTimeLimitCommand
+func limitCPUTime(_ *os.Process, seconds uint) (*cpuTimeLimiter, error) {
+	return &cpuTimeLimiter{seconds: seconds}, nil
+}
+
+// limitExceeded returns true if process with <state> was terminated by RLIMIT_CPU. Safe to call on nil limiter
+func (l *cpuTimeLimiter) limitExceeded(state *os.ProcessState) bool {
+	if l == nil || state == nil {
+		return false
+	}
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return false
+	}
+	switch ws.Signal() {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		// Hard limit is reached, the kernel kills only once CPU time is over it
+		return state.UserTime()+state.SystemTime() >= time.Duration(l.seconds+1)*time.Second
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// +build !linux,!windows
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// cpuTimeCheckInterval is an interval between process CPU time samples
+const cpuTimeCheckInterval = 250 * time.Millisecond
+
+// cpuTimeLimiter kills process once it consumed the allotted CPU time
+type cpuTimeLimiter struct {
+	exceeded int32
+}
+
+// cpuTimeLimitCommand returns <command> and <args> as is, as the limit is enforced by limitCPUTime
+func cpuTimeLimitCommand(command string, args []string, _ string, _ uint) (string, []string, error) {
+	return command, args, nil
+}
+
+// limitCPUTime kills process <p> once it consumed more than <seconds> of CPU time.
+//
+// RLIMIT_CPU can not be set for another process on this platform, so CPU time is sampled with ps instead,
+// until the process exits
+func limitCPUTime(p *os.Process, seconds uint) (*cpuTimeLimiter, error) {
+	l := &cpuTimeLimiter{}
+	limit := time.Duration(seconds) * time.Second
+	exited := processExited(p)
+
+	goTracked(func() {
+		ticker := time.NewTicker(cpuTimeCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exited:
+				return
+			case <-ticker.C:
+			}
+			out, err := exec.Command("ps", "-o", "time=", "-p", strconv.Itoa(p.Pid)).Output()
+			if err != nil {
+				return
+			}
+			used, err := parsePsTime(strings.TrimSpace(string(out)))
+			if err != nil {
+				return
+			}
+			if used >= limit {
+				atomic.StoreInt32(&l.exceeded, 1)
+				_ = p.Kill()
+				return
+			}
+		}
+	})
+
+	return l, nil
+}
+
+// parsePsTime parses CPU time printed by ps in [[dd-]hh:]mm:ss[.ss] format
+func parsePsTime(s string) (time.Duration, error) {
+	var total time.Duration
+	if idx := strings.Index(s, "-"); idx >= 0 {
+		days, err := strconv.Atoi(s[:idx])
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(days) * 24 * time.Hour
+		s = s[idx+1:]
+	}
+
+	parts := strings.Split(s, ":")
+	secs, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	total += time.Duration(secs * float64(time.Second))
+
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n) * unit
+		unit *= 60
+	}
+
+	return total, nil
+}
+
+// limitExceeded returns true if process with <state> was killed by the limiter. Safe to call on nil limiter
+func (l *cpuTimeLimiter) limitExceeded(state *os.ProcessState) bool {
+	return l != nil && state != nil && atomic.LoadInt32(&l.exceeded) == 1
+}
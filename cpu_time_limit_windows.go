@@ -0,0 +1,137 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	jobMsgEndOfProcessTime   = 1 // JOB_OBJECT_MSG_END_OF_PROCESS_TIME
+	jobMsgActiveProcessZero  = 4 // JOB_OBJECT_MSG_ACTIVE_PROCESS_ZERO
+	jobMsgExitProcess        = 7 // JOB_OBJECT_MSG_EXIT_PROCESS
+	jobMsgAbnormalExitProces = 8 // JOB_OBJECT_MSG_ABNORMAL_EXIT_PROCESS
+)
+
+// jobNotificationTimeout is a maximum time to wait for job object to report exit of process
+const jobNotificationTimeout = time.Second
+
+// jobObjectAssociateCompletionPort represents JOBOBJECT_ASSOCIATE_COMPLETION_PORT structure
+type jobObjectAssociateCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort windows.Handle
+}
+
+// cpuTimeLimiter watches job object limiting CPU time of process for reports of the limit being exceeded
+type cpuTimeLimiter struct {
+	exceeded int32
+	done     chan struct{} // Closed once exit of process is reported
+}
+
+// cpuTimeLimitCommand returns <command> and <args> as is, as the limit is enforced by limitCPUTime
+func cpuTimeLimitCommand(command string, args []string, _ string, _ uint) (string, []string, error) {
+	return command, args, nil
+}
+
+// limitCPUTime assigns process <p> to a job object limiting its user-mode CPU time to <seconds>
+func limitCPUTime(p *os.Process, seconds uint) (*cpuTimeLimiter, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	port, err := windows.CreateIoCompletionPort(windows.InvalidHandle, 0, 0, 1)
+	if err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+	closeAll := func() {
+		windows.CloseHandle(port)
+		windows.CloseHandle(job)
+	}
+
+	info := windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+		// In 100-nanosecond ticks
+		PerProcessUserTimeLimit: int64(seconds) * 10000000,
+		LimitFlags:              windows.JOB_OBJECT_LIMIT_PROCESS_TIME,
+	}
+	_, err = windows.SetInformationJobObject(
+		job,
+		windows.JobObjectBasicLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+	)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	// Job object reports processes terminated by the limit to the port
+	assoc := jobObjectAssociateCompletionPort{CompletionPort: port}
+	_, err = windows.SetInformationJobObject(
+		job,
+		windows.JobObjectAssociateCompletionPortInformation,
+		uintptr(unsafe.Pointer(&assoc)),
+		uint32(unsafe.Sizeof(assoc)),
+	)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	handle, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.Pid))
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	defer windows.CloseHandle(handle)
+	if err := windows.AssignProcessToJobObject(job, handle); err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	l := &cpuTimeLimiter{done: make(chan struct{})}
+	goTracked(func() {
+		defer close(l.done)
+		defer closeAll()
+		for {
+			var msg uint32
+			var key uintptr
+			// Process ID for process messages
+			var pid *windows.Overlapped
+			if err := windows.GetQueuedCompletionStatus(port, &msg, &key, &pid, windows.INFINITE); err != nil {
+				return
+			}
+			switch msg {
+			case jobMsgEndOfProcessTime:
+				if uintptr(unsafe.Pointer(pid)) == uintptr(p.Pid) {
+					atomic.StoreInt32(&l.exceeded, 1)
+				}
+			case jobMsgExitProcess, jobMsgAbnormalExitProces:
+				if uintptr(unsafe.Pointer(pid)) == uintptr(p.Pid) {
+					return
+				}
+			case jobMsgActiveProcessZero:
+				return
+			}
+		}
+	})
+	return l, nil
+}
+
+// limitExceeded returns true if process with <state> was terminated by job object CPU time limit. Safe to call on
+// nil limiter
+func (l *cpuTimeLimiter) limitExceeded(state *os.ProcessState) bool {
+	if l == nil || state == nil {
+		return false
+	}
+	// Exit is reported to the port independently of the process handle being signaled
+	select {
+	case <-l.done:
+	case <-time.After(jobNotificationTimeout):
+	}
+	return atomic.LoadInt32(&l.exceeded) == 1
+}
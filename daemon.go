@@ -0,0 +1,9 @@
+package executor
+
+// DaemonOptions represents options to start process as a traditional Unix daemon
+type DaemonOptions struct {
+	PidFile string // File to write PID of daemon into
+	LogFile string // File to append StdOut and StdErr of daemon to (discarded if empty)
+	Umask   int    // File mode creation mask of daemon
+	Chdir   string // Working directory of daemon ("/" if empty)
+}
@@ -0,0 +1,78 @@
+// +build !windows
+
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// daemonScript is run by intermediate shell: it starts the daemon in background and prints its PID.
+// Log file is passed as file descriptor 3
+const daemonScript = `umask %04o; "$0" "$@" </dev/null >&3 2>&3 3>&- & echo $!`
+
+// Daemonize starts process described by Options.Command, Options.Args and environment options (Options.EnvSpec,
+// Options.EnvFiles, Options.Env, Options.NoInheritEnv) as a daemon and returns its PID.
+// The daemon command, not the intermediate shell, is checked against the global policy and executable verification
+// options, in DaemonOptions.Chdir. The shell start is audited, the daemon itself is not watched after that.
+//
+// Double fork is done with an intermediate shell, started in a new session: it forks the daemon and exits,
+// so the daemon is reparented to init, is not a session leader and can never acquire a controlling terminal
+func Daemonize(opts Options, dopts DaemonOptions) (int, error) {
+	dir := dopts.Chdir
+	if dir == "" {
+		dir = "/"
+	}
+	if err := checkExecution(opts, dir); err != nil {
+		return 0, err
+	}
+
+	logPath := dopts.LogFile
+	if logPath == "" {
+		logPath = os.DevNull
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	args := append([]string{"-c", fmt.Sprintf(daemonScript, dopts.Umask), opts.Command}, opts.Args...)
+	cmd := exec.Command("/bin/sh", args...)
+	cmd.Dir = dir
+	cmd.ExtraFiles = []*os.File{logFile}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if cmd.Env, err = processEnv(opts); err != nil {
+		return 0, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := startAudited(cmd, opts.RedactEnv, cmd.Start); err != nil {
+		return 0, err
+	}
+
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	if err := cmd.Wait(); err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return 0, fmt.Errorf("can not get daemon PID: %v", err)
+	}
+
+	if dopts.PidFile != "" {
+		if err := os.WriteFile(dopts.PidFile, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+			return pid, err
+		}
+	}
+
+	return pid, nil
+}
@@ -0,0 +1,12 @@
+// +build windows
+
+package executor
+
+import (
+	"errors"
+)
+
+// Daemonize returns error as daemons are a Unix concept. See InstallService instead
+func Daemonize(opts Options, dopts DaemonOptions) (int, error) {
+	return 0, errors.New("daemonization is only supported on Unix, use InstallService instead")
+}
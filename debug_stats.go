@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Counters of resources held by the package
+var (
+	activeGoroutines int64
+	openPipes        int64
+)
+
+// DebugStats represents resources currently held by the package, to check none of them leak across executions
+type DebugStats struct {
+	Goroutines int64 // Running background goroutines, like output scanners and StdIn feeders
+	OpenPipes  int64 // Open pipe ends to StdIn, StdOut and StdErr of processes and between processes of Pipeline
+}
+
+// GetDebugStats returns resources currently held by the package
+func GetDebugStats() DebugStats {
+	return DebugStats{
+		Goroutines: atomic.LoadInt64(&activeGoroutines),
+		OpenPipes:  atomic.LoadInt64(&openPipes),
+	}
+}
+
+// goTracked runs <f> in a new goroutine, counting it as active until <f> returns
+func goTracked(f func()) {
+	atomic.AddInt64(&activeGoroutines, 1)
+	go func() {
+		defer atomic.AddInt64(&activeGoroutines, -1)
+		f()
+	}()
+}
+
+// pipesOpened counts <n> pipe ends as open
+func pipesOpened(n int64) {
+	atomic.AddInt64(&openPipes, n)
+}
+
+// trackedPipe is pipe end counted as open until it is closed the first time
+type trackedPipe struct {
+	r    io.Reader
+	w    io.Writer
+	c    io.Closer
+	once sync.Once
+	err  error
+}
+
+// trackPipe returns <pipe> (io.ReadCloser or io.WriteCloser) counted as open until closed
+func trackPipe(pipe io.Closer) *trackedPipe {
+	pipesOpened(1)
+	t := &trackedPipe{c: pipe}
+	t.r, _ = pipe.(io.Reader)
+	t.w, _ = pipe.(io.Writer)
+	return t
+}
+
+// Read implements io.Reader
+func (t *trackedPipe) Read(p []byte) (int, error) {
+	return t.r.Read(p)
+}
+
+// Write implements io.Writer
+func (t *trackedPipe) Write(p []byte) (int, error) {
+	return t.w.Write(p)
+}
+
+// Close implements io.Closer. Safe to call more than once
+func (t *trackedPipe) Close() error {
+	t.once.Do(func() {
+		t.err = t.c.Close()
+		pipesOpened(-1)
+	})
+	return t.err
+}
+
+// closePipes closes <pipes>, skipping nil ones
+func closePipes(pipes ...*trackedPipe) {
+	for _, pipe := range pipes {
+		if pipe != nil {
+			_ = pipe.Close()
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around changes in unified diff
+const diffContext = 3
+
+// diffMaxCells limits size of LCS table. Longer differing parts are reported as replaced entirely
+const diffMaxCells = 16 * 1024 * 1024
+
+// TimestampPattern matches common date and time formats, like RFC 3339, "2006-01-02 15:04:05" and "15:04:05.000"
+var TimestampPattern = regexp.MustCompile(
+	`\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}([.,]\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?|\b\d{2}:\d{2}:\d{2}([.,]\d+)?\b`)
+
+// TempPathPattern matches paths inside temporary directory of the current user and common temporary directories
+var TempPathPattern = regexp.MustCompile(tempPathRegexp())
+
+// DiffOptions represents options to compare outputs of two runs
+type DiffOptions struct {
+	IgnorePatterns []*regexp.Regexp // Text matching any of these is considered equal, like TimestampPattern
+	NameA          string           // Name of the first result in diff header ("a" if not set)
+	NameB          string           // Name of the second result in diff header ("b" if not set)
+}
+
+// Diff returns unified diff of Result.Output of <a> and <b>, or empty string if they are equal
+// after masking text matching DiffOptions.IgnorePatterns
+func Diff(a Result, b Result, dopts DiffOptions) string {
+	nameA, nameB := dopts.NameA, dopts.NameB
+	if nameA == "" {
+		nameA = "a"
+	}
+	if nameB == "" {
+		nameB = "b"
+	}
+	outA, outB := a.Output, b.Output
+	for _, re := range dopts.IgnorePatterns {
+		outA = re.ReplaceAllLiteralString(outA, "<ignored>")
+		outB = re.ReplaceAllLiteralString(outB, "<ignored>")
+	}
+	return unifiedDiff(nameA, nameB, outA, outB)
+}
+
+// tempPathRegexp returns pattern of paths inside temporary directories, without spaces
+func tempPathRegexp() string {
+	dirs := []string{regexp.QuoteMeta(strings.TrimRight(os.TempDir(), `/\`)), `/tmp`, `/var/folders`}
+	return `(` + strings.Join(dirs, "|") + `)[/\\][^\s"']*`
+}
+
+// diffLine represents a line of edit script: ' ' is unchanged, '-' is removed and '+' is added line
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff returns unified diff of <a> named <nameA> and <b> named <nameB>, or empty string if they are equal
+func unifiedDiff(nameA string, nameB string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	// Positions of each edit script line in <a> and <b>
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if op.op != '+' {
+			aPos[i+1]++
+		}
+		if op.op != '-' {
+			bPos[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %v\n+++ %v\n", nameA, nameB)
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].op == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// Merge changes separated by less than two contexts into one hunk
+		end := i
+		for end < len(ops) {
+			if ops[end].op != ' ' {
+				end++
+				continue
+			}
+			run := 0
+			for end+run < len(ops) && ops[end+run].op == ' ' {
+				run++
+			}
+			if end+run == len(ops) || run > 2*diffContext {
+				break
+			}
+			end += run
+		}
+		stop := end + diffContext
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		aStart, aLen := aPos[start], aPos[stop]-aPos[start]
+		bStart, bLen := bPos[start], bPos[stop]-bPos[start]
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&sb, "@@ -%v,%v +%v,%v @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[start:stop] {
+			sb.WriteByte(op.op)
+			sb.WriteString(op.text)
+			sb.WriteByte('\n')
+		}
+		i = stop
+	}
+	return sb.String()
+}
+
+// splitLines splits <s> into lines without line endings
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns edit script turning <a> into <b>, based on their longest common subsequence
+func diffLines(a []string, b []string) []diffLine {
+	var ops []diffLine
+
+	// Common prefix and suffix don't need the table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffLine{' ', a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	tail := a[len(a)-suffix:]
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	n, m := len(a), len(b)
+	if (n+1)*(m+1) > diffMaxCells {
+		for _, line := range a {
+			ops = append(ops, diffLine{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffLine{'+', line})
+		}
+	} else {
+		// lcs[i][j] is length of LCS of a[i:] and b[j:]
+		lcs := make([][]int, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && a[i] == b[j]:
+				ops = append(ops, diffLine{' ', a[i]})
+				i++
+				j++
+			case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffLine{'-', a[i]})
+				i++
+			default:
+				ops = append(ops, diffLine{'+', b[j]})
+				j++
+			}
+		}
+	}
+
+	for _, line := range tail {
+		ops = append(ops, diffLine{' ', line})
+	}
+	return ops
+}
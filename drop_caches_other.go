@@ -0,0 +1,12 @@
+// +build !linux
+
+package executor
+
+import (
+	"errors"
+)
+
+// dropCaches returns error as dropping caches is not supported on this platform
+func dropCaches() error {
+	return errors.New("dropping caches is only supported on Linux")
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// executionLevelRe matches requested execution level of application manifest
+var executionLevelRe = regexp.MustCompile(`requestedExecutionLevel[^>]*\blevel\s*=\s*["']([A-Za-z]+)["']`)
+
+// installerNameParts are parts of executable names UAC installer detection elevates if they have no manifest
+var installerNameParts = []string{"setup", "install", "update", "patch"}
+
+// manifestRequiresElevation returns true if application <manifest> of executable at <path> requests administrator
+// privileges. highestAvailable does so only if user is an administrator (<admin>), standard users run it as is.
+// Executables without manifest are checked against UAC installer detection by their names, which applies only to
+// 32-bit images (<is32Bit>)
+func manifestRequiresElevation(path string, manifest []byte, admin bool, is32Bit bool) bool {
+	if len(manifest) > 0 {
+		m := executionLevelRe.FindSubmatch(manifest)
+		if m == nil {
+			return false
+		}
+		switch strings.ToLower(string(m[1])) {
+		case "requireadministrator":
+			return true
+		case "highestavailable":
+			return admin
+		}
+		return false
+	}
+	if !is32Bit {
+		return false
+	}
+	name := strings.ToLower(filepath.Base(path))
+	for _, part := range installerNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
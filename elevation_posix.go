@@ -0,0 +1,17 @@
+// +build !windows
+
+package executor
+
+import (
+	"os"
+)
+
+// IsElevated returns true if the current process runs as root
+func IsElevated() bool {
+	return os.Geteuid() == 0
+}
+
+// RequiresElevation returns false, as executables do not request privileges on this platform
+func RequiresElevation(command string) (bool, error) {
+	return false, nil
+}
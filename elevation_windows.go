@@ -0,0 +1,77 @@
+// +build windows
+
+package executor
+
+import (
+	"debug/pe"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// tokenElevationTypeDefault is elevation type of token of standard user, or of any user if UAC is disabled
+const tokenElevationTypeDefault = 1
+
+// IsElevated returns true if the current process runs with elevated token, so the processes it starts get
+// administrator privileges without UAC prompt
+func IsElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// RequiresElevation returns true if executable of <command> (resolved by PATH) requests administrator privileges with
+// requestedExecutionLevel of its manifest (highestAvailable, if the current user is an administrator), or is elevated
+// by UAC installer detection having no manifest.
+// Starting such executable from non-elevated process fails with ERROR_ELEVATION_REQUIRED, as Start does not prompt,
+// so it should be started with Self (Elevated) or by the Task Scheduler
+func RequiresElevation(command string) (bool, error) {
+	path, err := exec.LookPath(normalizeCommandPath(command))
+	if err != nil {
+		return false, err
+	}
+	module, err := windows.LoadLibraryEx(path, 0, windows.LOAD_LIBRARY_AS_DATAFILE|windows.LOAD_LIBRARY_AS_IMAGE_RESOURCE)
+	if err != nil {
+		return false, err
+	}
+	defer windows.FreeLibrary(module)
+
+	var manifest []byte
+	res, err := windows.FindResource(module, windows.CREATEPROCESS_MANIFEST_RESOURCE_ID, windows.RT_MANIFEST)
+	if err == nil {
+		manifest, err = windows.LoadResourceData(module, res)
+		if err != nil {
+			return false, err
+		}
+	}
+	is32Bit := false
+	if manifest == nil {
+		if is32Bit, err = is32BitImage(path); err != nil {
+			return false, err
+		}
+	}
+	return manifestRequiresElevation(path, manifest, isAdministrator(), is32Bit), nil
+}
+
+// isAdministrator returns true if the current user can get administrator privileges, having either split token of
+// administrator under UAC (limited or elevated), so highestAvailable executables are elevated for them
+func isAdministrator() bool {
+	var elevationType uint32
+	var n uint32
+	err := windows.GetTokenInformation(windows.GetCurrentProcessToken(), windows.TokenElevationType,
+		(*byte)(unsafe.Pointer(&elevationType)), uint32(unsafe.Sizeof(elevationType)), &n)
+	if err != nil {
+		diagln(err)
+		return false
+	}
+	return elevationType != tokenElevationTypeDefault
+}
+
+// is32BitImage returns true if executable at <path> is x86 image, which UAC installer detection applies to
+func is32BitImage(path string) (bool, error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	return f.Machine == pe.IMAGE_FILE_MACHINE_I386, nil
+}
@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// invalidUTF8Byte is emitted by decoders in place of undecodable characters, to apply Options.InvalidBytes to them
+const invalidUTF8Byte = 0xff
+
+// InvalidBytes represents what to do with bytes of output which are not valid characters
+type InvalidBytes int
+
+const (
+	InvalidReplace InvalidBytes = iota // Replace with U+FFFD replacement character
+	InvalidSkip                        // Remove from output
+	InvalidError                       // Replace with U+FFFD and report position of the first one in Result.DecodeError
+)
+
+// DecodeError represents position of the first invalid byte in output
+type DecodeError struct {
+	Stream string // "stdout" or "stderr"
+	Offset int64  // Offset of the byte in stream after decoding and transforms
+}
+
+// Error implements error
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v at offset %v of %v", ErrInvalidOutput, e.Offset, e.Stream)
+}
+
+// Unwrap returns ErrInvalidOutput
+func (e *DecodeError) Unwrap() error {
+	return ErrInvalidOutput
+}
+
+// replacementChar is UTF-8 encoding of U+FFFD
+var replacementChar = []byte(string(utf8.RuneError))
+
+// runeSplitter returns bufio.SplitFunc splitting UTF-8 text into characters, applying <policy> to invalid bytes.
+// <onInvalid> is called with offset of each invalid byte
+func runeSplitter(policy InvalidBytes, onInvalid func(offset int64)) bufio.SplitFunc {
+	var offset int64
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanRunes(data, atEOF)
+		if advance == 0 {
+			return advance, token, err
+		}
+		start := offset
+		offset += int64(advance)
+		if r, width := utf8.DecodeRune(data); r != utf8.RuneError || width != 1 {
+			return advance, token, err
+		}
+		onInvalid(start)
+		if policy == InvalidSkip {
+			return advance, nil, err
+		}
+		return advance, replacementChar, err
+	}
+}
+
+// CodePage represents Windows code page identifier of process output encoding
+type CodePage uint32
+
+const (
+	CodePage437     CodePage = 437   // OEM United States
+	CodePage850     CodePage = 850   // OEM Multilingual Latin 1
+	CodePage866     CodePage = 866   // OEM Russian
+	CodePage932     CodePage = 932   // Japanese Shift-JIS
+	CodePage936     CodePage = 936   // Simplified Chinese GBK
+	CodePage1251    CodePage = 1251  // ANSI Cyrillic
+	CodePage1252    CodePage = 1252  // ANSI Latin 1
+	CodePageUTF16LE CodePage = 1200  // UTF-16 little-endian, like output of "cmd /U" (big-endian if output starts with its BOM)
+	CodePageUTF16BE CodePage = 1201  // UTF-16 big-endian (little-endian if output starts with its BOM)
+	CodePageUTF8    CodePage = 65001 // UTF-8, output is not decoded
+)
+
+// decodeFunc converts bytes of <p> into UTF-8 and returns the result and the number of bytes consumed.
+// Bytes of incomplete trailing character are left unconsumed unless <final> is set
+type decodeFunc func(p []byte, final bool) ([]byte, int)
+
+// newDecoder returns function decoding text in code page <cp>. UTF-16 is decoded on every platform
+func newDecoder(cp CodePage) (decodeFunc, error) {
+	switch cp {
+	case CodePageUTF16LE:
+		return utf16Decoder(false), nil
+	case CodePageUTF16BE:
+		return utf16Decoder(true), nil
+	}
+	return codePageDecoder(cp)
+}
+
+// encodeFunc converts complete UTF-8 text of <p> into some encoding
+type encodeFunc func(p []byte) []byte
+
+// newEncoder returns function encoding UTF-8 text into code page <cp>, or nil if <cp> is UTF-8
+func newEncoder(cp CodePage) (encodeFunc, error) {
+	switch cp {
+	case CodePageUTF8:
+		return nil, nil
+	case CodePageUTF16LE:
+		return utf16Encoder(false), nil
+	case CodePageUTF16BE:
+		return utf16Encoder(true), nil
+	}
+	return codePageEncoder(cp)
+}
+
+// decodingReader converts text in some encoding from the underlying reader into UTF-8
+type decodingReader struct {
+	r      io.Reader
+	decode decodeFunc
+	buf    []byte
+	in     []byte // Read, but not yet decoded bytes
+	out    []byte // Decoded, but not yet returned bytes
+	err    error
+}
+
+// newDecodingReader returns reader decoding data from <r> with <decode>
+func newDecodingReader(r io.Reader, decode decodeFunc) *decodingReader {
+	return &decodingReader{r: r, decode: decode, buf: make([]byte, 4096)}
+}
+
+// Read implements io.Reader
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		n, err := d.r.Read(d.buf)
+		d.in = append(d.in, d.buf[:n]...)
+		d.err = err
+		out, used := d.decode(d.in, err != nil)
+		d.out = append(d.out[:0], out...)
+		d.in = append(d.in[:0], d.in[used:]...)
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
@@ -0,0 +1,22 @@
+// +build !windows
+
+package executor
+
+import (
+	"fmt"
+)
+
+// defaultCodePage returns UTF-8, as it is the encoding of the terminals on this platform
+func defaultCodePage() CodePage {
+	return CodePageUTF8
+}
+
+// codePageDecoder returns error, as decoding of code pages other than UTF-8 is only supported on Windows
+func codePageDecoder(cp CodePage) (decodeFunc, error) {
+	return nil, fmt.Errorf("decoding of code page %v is only supported on Windows", cp)
+}
+
+// codePageEncoder returns error, as encoding into code pages other than UTF-8 is only supported on Windows
+func codePageEncoder(cp CodePage) (encodeFunc, error) {
+	return nil, fmt.Errorf("encoding into code page %v is only supported on Windows", cp)
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// utf16Decoder returns function decoding UTF-16 text, big-endian if <bigEndian> is set.
+// Byte order mark at the beginning of text is removed and selects byte order it denotes.
+// Odd trailing byte and high surrogate without the low one are left unconsumed until more bytes are read
+func utf16Decoder(bigEndian bool) decodeFunc {
+	bomChecked := false
+	return func(p []byte, final bool) ([]byte, int) {
+		n := 0
+		if !bomChecked {
+			if len(p) < 2 && !final {
+				return nil, 0
+			}
+			bomChecked = true
+			if len(p) >= 2 {
+				switch {
+				case p[0] == 0xff && p[1] == 0xfe:
+					bigEndian = false
+					n = 2
+				case p[0] == 0xfe && p[1] == 0xff:
+					bigEndian = true
+					n = 2
+				}
+			}
+		}
+
+		end := n + (len(p)-n)&^1
+		units := make([]uint16, 0, (end-n)/2)
+		for i := n; i < end; i += 2 {
+			if bigEndian {
+				units = append(units, uint16(p[i])<<8|uint16(p[i+1]))
+			} else {
+				units = append(units, uint16(p[i+1])<<8|uint16(p[i]))
+			}
+		}
+		if !final && len(units) > 0 && utf16.IsSurrogate(rune(units[len(units)-1])) &&
+			units[len(units)-1] < 0xdc00 {
+			units = units[:len(units)-1]
+			end -= 2
+		}
+
+		out := make([]byte, 0, len(units))
+		var buf [utf8.UTFMax]byte
+		for i := 0; i < len(units); i++ {
+			r := rune(units[i])
+			if utf16.IsSurrogate(r) {
+				r = utf8.RuneError
+				if units[i] < 0xdc00 && i+1 < len(units) {
+					if pair := utf16.DecodeRune(rune(units[i]), rune(units[i+1])); pair != utf8.RuneError {
+						r = pair
+						i++
+					}
+				}
+				if r == utf8.RuneError {
+					// Unpaired surrogate is marked with invalid UTF-8 byte for the scanner
+					out = append(out, invalidUTF8Byte)
+					continue
+				}
+			}
+			size := utf8.EncodeRune(buf[:], r)
+			out = append(out, buf[:size]...)
+		}
+		if final && end < len(p) {
+			out = append(out, invalidUTF8Byte)
+			end = len(p)
+		}
+		return out, end
+	}
+}
+
+// utf16Encoder returns function encoding UTF-8 text into UTF-16 without byte order mark, big-endian if <bigEndian> is set
+func utf16Encoder(bigEndian bool) encodeFunc {
+	return func(p []byte) []byte {
+		units := utf16.Encode([]rune(string(p)))
+		out := make([]byte, 0, len(units)*2)
+		for _, unit := range units {
+			if bigEndian {
+				out = append(out, byte(unit>>8), byte(unit))
+			} else {
+				out = append(out, byte(unit), byte(unit>>8))
+			}
+		}
+		return out
+	}
+}
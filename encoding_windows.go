@@ -0,0 +1,133 @@
+// +build windows
+
+package executor
+
+import (
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32             = windows.NewLazySystemDLL("kernel32.dll")
+	procGetConsoleOutputCP  = modkernel32.NewProc("GetConsoleOutputCP")
+	procGetOEMCP            = modkernel32.NewProc("GetOEMCP")
+	procIsDBCSLeadByteEx    = modkernel32.NewProc("IsDBCSLeadByteEx")
+	procWideCharToMultiByte = modkernel32.NewProc("WideCharToMultiByte")
+)
+
+// defaultCodePage returns output code page of the attached console, or OEM code page if there is no console
+func defaultCodePage() CodePage {
+	if cp, _, _ := procGetConsoleOutputCP.Call(); cp != 0 {
+		return CodePage(cp)
+	}
+	cp, _, _ := procGetOEMCP.Call()
+	return CodePage(cp)
+}
+
+// codePageDecoder returns function decoding text in code page <cp> with MultiByteToWideChar
+func codePageDecoder(cp CodePage) (decodeFunc, error) {
+	// Lead bytes of double-byte code pages must not be decoded without the trailing byte
+	var lead [256]bool
+	for b := 0; b < 256; b++ {
+		r, _, _ := procIsDBCSLeadByteEx.Call(uintptr(cp), uintptr(b))
+		lead[b] = r != 0
+	}
+
+	// Check code page is installed
+	if _, err := windows.MultiByteToWideChar(uint32(cp), 0, &[]byte{'a'}[0], 1, nil, 0); err != nil {
+		return nil, err
+	}
+
+	return func(p []byte, final bool) ([]byte, int) {
+		n := 0
+		for n < len(p) {
+			if lead[p[n]] {
+				if n+1 >= len(p) && !final {
+					break
+				}
+				n += 2
+			} else {
+				n++
+			}
+		}
+		if n > len(p) {
+			n = len(p)
+		}
+		if n == 0 {
+			return nil, 0
+		}
+
+		if out, ok := multiByteToUTF8(cp, p[:n]); ok {
+			return out, n
+		}
+		// Decode character by character, marking undecodable ones with invalid UTF-8 byte for the scanner
+		var out []byte
+		for i := 0; i < n; {
+			width := 1
+			if lead[p[i]] && i+1 < n {
+				width = 2
+			}
+			if dec, ok := multiByteToUTF8(cp, p[i:i+width]); ok {
+				out = append(out, dec...)
+			} else {
+				out = append(out, invalidUTF8Byte)
+			}
+			i += width
+		}
+		return out, n
+	}, nil
+}
+
+// mbErrInvalidChars is MultiByteToWideChar flag MB_ERR_INVALID_CHARS
+const mbErrInvalidChars = 0x8
+
+// multiByteToUTF8 converts non-empty <p> in code page <cp> into UTF-8. Returns false if <p> contains undecodable bytes
+func multiByteToUTF8(cp CodePage, p []byte) ([]byte, bool) {
+	size, err := windows.MultiByteToWideChar(uint32(cp), mbErrInvalidChars, &p[0], int32(len(p)), nil, 0)
+	if err != nil || size == 0 {
+		return nil, false
+	}
+	wide := make([]uint16, size)
+	if _, err := windows.MultiByteToWideChar(uint32(cp), mbErrInvalidChars, &p[0], int32(len(p)), &wide[0], size); err != nil {
+		return nil, false
+	}
+	return []byte(string(utf16.Decode(wide))), true
+}
+
+// codePageEncoder returns function encoding text into code page <cp> with WideCharToMultiByte.
+// Characters missing in the code page are replaced with its default character
+func codePageEncoder(cp CodePage) (encodeFunc, error) {
+	// Check code page is installed
+	if _, err := wideCharToMultiByte(cp, []uint16{'a'}); err != nil {
+		return nil, err
+	}
+
+	return func(p []byte) []byte {
+		if len(p) == 0 {
+			return nil
+		}
+		out, err := wideCharToMultiByte(cp, utf16.Encode([]rune(string(p))))
+		if err != nil {
+			return p
+		}
+		return out
+	}, nil
+}
+
+// wideCharToMultiByte converts non-empty <wide> into code page <cp>
+func wideCharToMultiByte(cp CodePage, wide []uint16) ([]byte, error) {
+	size, _, err := procWideCharToMultiByte.Call(uintptr(cp), 0, uintptr(unsafe.Pointer(&wide[0])), uintptr(len(wide)),
+		0, 0, 0, 0)
+	if size == 0 {
+		return nil, err
+	}
+	out := make([]byte, size)
+	size, _, err = procWideCharToMultiByte.Call(uintptr(cp), 0, uintptr(unsafe.Pointer(&wide[0])), uintptr(len(wide)),
+		uintptr(unsafe.Pointer(&out[0])), size, 0, 0)
+	if size == 0 {
+		return nil, err
+	}
+	return out[:size], nil
+}
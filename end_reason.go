@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"context"
+	"os"
+)
+
+// EndReason represents why execution of a process ended
+type EndReason int
+
+const (
+	EndNone         EndReason = iota // Process was not started or not waited for
+	EndExited                        // Process exited by itself
+	EndTimeout                       // Process was killed after Options.Timeout or deadline of Options.Context
+	EndCanceled                      // Process was killed on cancellation of Options.Context
+	EndSignaled                      // Process was terminated by a signal from outside
+	EndMemoryLimit                   // Process was killed for exceeding Options.MemoryLimit
+	EndCPUTimeLimit                  // Process was killed for exceeding Options.CPUTimeLimit
+)
+
+// String returns name of the reason
+func (r EndReason) String() string {
+	switch r {
+	case EndExited:
+		return "exited"
+	case EndTimeout:
+		return "timeout"
+	case EndCanceled:
+		return "canceled"
+	case EndSignaled:
+		return "signaled"
+	case EndMemoryLimit:
+		return "memory limit"
+	case EndCPUTimeLimit:
+		return "CPU time limit"
+	default:
+		return "none"
+	}
+}
+
+// endReason returns why process with <state> ended, given it ran with <ctx> and its limit flags are set in <res>
+func endReason(res Result, state *os.ProcessState, ctx context.Context) EndReason {
+	switch {
+	case state == nil:
+		return EndNone
+	case res.MemoryLimitExceeded:
+		return EndMemoryLimit
+	case res.CPUTimeLimitExceeded:
+		return EndCPUTimeLimit
+	case ctx.Err() == context.DeadlineExceeded:
+		return EndTimeout
+	case ctx.Err() == context.Canceled:
+		return EndCanceled
+	case !state.Exited():
+		return EndSignaled
+	default:
+		return EndExited
+	}
+}
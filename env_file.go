@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadEnvFiles reads .env files in order and returns their variables in "KEY=VALUE" form.
+// Variables from later files override ones from earlier files
+func loadEnvFiles(paths []string) ([]string, error) {
+	var env []string
+	for _, path := range paths {
+		fileEnv, err := parseEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, fileEnv...)
+	}
+	return env, nil
+}
+
+// parseEnvFile parses a single .env file.
+//
+// Supported syntax:
+//
+//	# comment
+//	KEY=value            # trailing comment
+//	export KEY=value
+//	KEY='literal value'
+//	KEY="value with \"escapes\"\n"
+func parseEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export") && len(line) > 6 && (line[6] == ' ' || line[6] == '\t') {
+			line = strings.TrimSpace(line[6:])
+		}
+
+		idx := strings.Index(line, "=")
+		if idx < 1 {
+			return nil, fmt.Errorf("%v:%v: invalid line: %q", path, lineNum, line)
+		}
+		key := strings.TrimSpace(line[:idx])
+		if strings.ContainsAny(key, " \t'\"") {
+			return nil, fmt.Errorf("%v:%v: invalid variable name: %q", path, lineNum, key)
+		}
+		value, err := parseEnvValue(strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, lineNum, err)
+		}
+		env = append(env, key+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// parseEnvValue unquotes the value part of a .env line
+func parseEnvValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	switch raw[0] {
+	case '\'':
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		if err := checkEnvTrailer(raw[end+2:]); err != nil {
+			return "", err
+		}
+		return raw[1 : end+1], nil
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == '"' {
+				if err := checkEnvTrailer(raw[i+1:]); err != nil {
+					return "", err
+				}
+				return sb.String(), nil
+			}
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					sb.WriteByte(raw[i])
+				}
+				continue
+			}
+			sb.WriteByte(c)
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	default:
+		// Strip trailing comment of unquoted value
+		if idx := strings.Index(raw, " #"); idx >= 0 {
+			raw = raw[:idx]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// checkEnvTrailer returns error if <rest> after closing quote of value is neither empty nor a comment
+func checkEnvTrailer(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected text after quoted value: %q", rest)
+	}
+	return nil
+}
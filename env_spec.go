@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// EnvSpec represents environment of process composed of several sources. Sources are applied in order of
+// increasing precedence: environment of this process (if Inherit is set), Files, Vars, then Computed
+type EnvSpec struct {
+	Inherit  bool                                            // Start with environment of this process (of logged on user with Options.UserSession)?
+	Files    []string                                        // .env files to load (later files override earlier)
+	Vars     map[string]string                               // Variables to set
+	Computed map[string]func(current string) (string, error) // Functions returning value of variable from its current value ("" if not set), like to prepend to PATH
+	Strict   bool                                            // Fail if a file or Vars change variable set by earlier file?
+}
+
+// EnvConflict represents variable set by a file or Vars of EnvSpec and changed by a later file or Vars
+type EnvConflict struct {
+	Name      string // Variable name
+	OldValue  string // Value set by earlier source
+	NewValue  string // Value set by later source
+	OldSource string // Earlier source: file path or "vars"
+	NewSource string // Later source
+}
+
+// String returns description of the conflict
+func (c EnvConflict) String() string {
+	return fmt.Sprintf("%v=%q from %v is overridden with %q from %v", c.Name, c.OldValue, c.OldSource, c.NewValue, c.NewSource)
+}
+
+// envVar is variable of environment being built, with the source it came from
+type envVar struct {
+	name   string
+	value  string
+	source string
+}
+
+// envBuilder builds environment from sources, keeping order of first appearance of variables
+type envBuilder struct {
+	vars      []envVar
+	index     map[string]int // Index of variable in vars by key of its name
+	conflicts []EnvConflict
+}
+
+// envKey returns key of variable <name>, case-insensitive on Windows
+func envKey(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// set sets variable <name> to <value> from <source>, recording conflict with earlier non-inherited value.
+// Computed values are derived from the current ones deliberately, so they never conflict
+func (b *envBuilder) set(name string, value string, source string) {
+	key := envKey(name)
+	i, ok := b.index[key]
+	if !ok {
+		b.index[key] = len(b.vars)
+		b.vars = append(b.vars, envVar{name: name, value: value, source: source})
+		return
+	}
+	old := b.vars[i]
+	if old.value != value && old.source != envSourceInherited && source != envSourceComputed {
+		b.conflicts = append(b.conflicts, EnvConflict{
+			Name:      name,
+			OldValue:  old.value,
+			NewValue:  value,
+			OldSource: old.source,
+			NewSource: source,
+		})
+	}
+	b.vars[i] = envVar{name: old.name, value: value, source: source}
+}
+
+// get returns value of variable <name>, or "" if it is not set
+func (b *envBuilder) get(name string) string {
+	if i, ok := b.index[envKey(name)]; ok {
+		return b.vars[i].value
+	}
+	return ""
+}
+
+// setPairs sets variables of <env> in "KEY=VALUE" form from <source>
+func (b *envBuilder) setPairs(env []string, source string) {
+	for _, pair := range env {
+		// Windows has hidden variables like "=C:=C:\dir", with names starting with "="
+		if pair == "" {
+			continue
+		}
+		idx := strings.Index(pair[1:], "=") + 1
+		if idx < 1 {
+			continue
+		}
+		b.set(pair[:idx], pair[idx+1:], source)
+	}
+}
+
+// Sources of variables, other than file paths
+const (
+	envSourceInherited = "inherited"
+	envSourceVars      = "vars"
+	envSourceComputed  = "computed"
+)
+
+// Build returns environment in "KEY=VALUE" form and conflicts of its sources.
+// Returns error if a file can not be loaded or computed, or if Strict is set and there are conflicts
+func (s EnvSpec) Build() ([]string, []EnvConflict, error) {
+	return s.build(environ)
+}
+
+// build is Build with environment to inherit returned by <inherited>
+func (s EnvSpec) build(inherited func() ([]string, error)) ([]string, []EnvConflict, error) {
+	b := &envBuilder{index: map[string]int{}}
+	if s.Inherit {
+		env, err := inherited()
+		if err != nil {
+			return nil, nil, err
+		}
+		b.setPairs(env, envSourceInherited)
+	}
+	for _, path := range s.Files {
+		fileEnv, err := parseEnvFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.setPairs(fileEnv, path)
+	}
+	for _, name := range sortedKeys(s.Vars) {
+		b.set(name, s.Vars[name], envSourceVars)
+	}
+	names := make([]string, 0, len(s.Computed))
+	for name := range s.Computed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := s.Computed[name](b.get(name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("compute %v: %v", name, err)
+		}
+		b.set(name, value, envSourceComputed)
+	}
+
+	if s.Strict && len(b.conflicts) > 0 {
+		return nil, b.conflicts, fmt.Errorf("conflicting environment: %v", b.conflicts[0])
+	}
+	return b.env(), b.conflicts, nil
+}
+
+// env returns built environment in "KEY=VALUE" form
+func (b *envBuilder) env() []string {
+	env := make([]string, len(b.vars))
+	for i, v := range b.vars {
+		env[i] = v.name + "=" + v.value
+	}
+	return env
+}
+
+// dedupEnv returns <env> with only the last value of each variable, like exec.Cmd does
+func dedupEnv(env []string) []string {
+	b := &envBuilder{index: map[string]int{}}
+	b.setPairs(env, envSourceInherited)
+	return b.env()
+}
+
+// environ returns environment of this process
+func environ() ([]string, error) {
+	return os.Environ(), nil
+}
+
+// processEnv returns environment of process described by <opts>: EnvSpec (or inherited environment, unless
+// Options.NoInheritEnv is set), overridden by Options.EnvFiles, then by Options.Env. Environment is inherited from
+// this process, or from the logged on user if Options.UserSession is set.
+// Returns nil if environment is inherited as is
+func processEnv(opts Options) ([]string, error) {
+	inherited := environ
+	if opts.UserSession {
+		inherited = userSessionEnviron
+	}
+
+	var env []string
+	if opts.EnvSpec != nil {
+		specEnv, conflicts, err := opts.EnvSpec.build(inherited)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range conflicts {
+			diagf("environment conflict: %v\n", c)
+		}
+		env = specEnv
+	} else if opts.NoInheritEnv {
+		env = []string{}
+	}
+	if len(opts.EnvFiles) == 0 && len(opts.Env) == 0 {
+		return env, nil
+	}
+
+	fileEnv, err := loadEnvFiles(opts.EnvFiles)
+	if err != nil {
+		return nil, err
+	}
+	if env == nil {
+		if env, err = inherited(); err != nil {
+			return nil, err
+		}
+	}
+	// Later values of the same variable win
+	env = append(env, fileEnv...)
+	return append(env, opts.Env...), nil
+}
+
+// sortedKeys returns keys of <m> in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
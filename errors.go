@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"errors"
+)
+
+// ErrMemoryLimit is reported when process was killed for exceeding Options.MemoryLimit
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// ErrCPUTimeLimit is reported when process was killed for exceeding Options.CPUTimeLimit
+var ErrCPUTimeLimit = errors.New("CPU time limit exceeded")
+
+// ErrPolicyDenied is reported when the Policy set with SetPolicy rejected execution
+var ErrPolicyDenied = errors.New("execution denied by policy")
+
+// ErrShellMeta is reported when a value contains characters interpreted by shells
+var ErrShellMeta = errors.New("value contains shell metacharacter")
+
+// ErrChecksumMismatch is reported when executable does not match Options.ExpectedSHA256
+var ErrChecksumMismatch = errors.New("executable checksum mismatch")
+
+// ErrUnsignedBinary is reported when executable has no valid code signature of expected signer
+var ErrUnsignedBinary = errors.New("executable is not signed")
+
+// ErrNonZeroExit is reported when process exited with non-zero code or was terminated by a signal
+var ErrNonZeroExit = errors.New("process exited with non-zero code")
+
+// ErrTimeout is reported when process was killed after Options.Timeout or deadline of Options.Context
+var ErrTimeout = errors.New("process timed out")
+
+// ErrCanceled is reported when process was killed on cancellation of Options.Context
+var ErrCanceled = errors.New("process canceled")
+
+// ErrNotStarted is reported when process could not be started
+var ErrNotStarted = errors.New("process did not start")
+
+// ErrInvalidJSON is reported when process output can not be decoded as JSON
+var ErrInvalidJSON = errors.New("invalid JSON output")
+
+// ErrOutputMatched is reported when output line matched Options.FailIfOutputMatches
+var ErrOutputMatched = errors.New("output matched failure pattern")
+
+// ErrOutputMissing is reported when no output line matched Options.RequireOutputMatch
+var ErrOutputMissing = errors.New("required output is missing")
+
+// ErrUnsupported is reported when an option can't be honored on the current platform, see Capabilities
+var ErrUnsupported = errors.New("option is not supported on this platform")
+
+// ErrInvalidOutput is reported when output contains bytes which are not valid characters
+var ErrInvalidOutput = errors.New("invalid character in output")
@@ -0,0 +1,61 @@
+// +build !windows
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// Exec replaces the current process with process described by Options.Command, Options.Args, Options.Dir
+// and environment options (Options.EnvSpec, Options.EnvFiles, Options.Env, Options.NoInheritEnv), other options
+// are ignored. Fails without replacing anything if the global policy denies the command or its executable fails
+// verification. Only the start is audited, as nothing is left to see the replacement finish. Returns only on failure
+func Exec(opts Options) error {
+	if err := checkExecution(opts, opts.Dir); err != nil {
+		return err
+	}
+	path, err := resolveCommand(opts.Command, opts.Dir)
+	if err != nil {
+		return err
+	}
+	// Path found in relative directory of PATH stays valid after changing directory
+	if path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+
+	env, err := processEnv(opts)
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	env = dedupEnv(env)
+
+	// Process is audited as started beforehand, as nothing runs here after successful exec
+	argv := append([]string{opts.Command}, opts.Args...)
+	cmd := &exec.Cmd{Path: path, Args: argv, Env: env, Dir: opts.Dir}
+	res := Result{ExitCode: -1, Trace: newExecTrace(cmd, opts.RedactEnv), StartOk: true, PID: os.Getpid()}
+	debugTrace(res.Trace)
+
+	if opts.Dir != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if err := os.Chdir(opts.Dir); err != nil {
+			return err
+		}
+		// Failed exec leaves the current process as it was
+		defer func() { _ = os.Chdir(wd) }()
+	}
+
+	audit(AuditStart, res, nil)
+	err = syscall.Exec(path, argv, env)
+	res.StartOk = false
+	audit(AuditFinish, res, nil)
+	return err
+}
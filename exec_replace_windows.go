@@ -0,0 +1,46 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// Exec emulates replacing the current process with process described by Options.Command, Options.Args,
+// Options.Dir and environment options (Options.EnvSpec, Options.EnvFiles, Options.Env, Options.NoInheritEnv),
+// other options are ignored. The command is checked against the global policy and executable verification options
+// like in Start, and both its start and finish are audited.
+//
+// Windows can not replace a running process, so the process is started with inherited console and standard streams,
+// Ctrl+C is left for it to handle, and the current process exits with its exit code once it finished.
+// Returns only on failure to start
+func Exec(opts Options) error {
+	if err := checkExecution(opts, opts.Dir); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(opts.Command, opts.Args...)
+	cmd.Dir = opts.Dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	env, err := processEnv(opts)
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+
+	res, err := startAudited(cmd, opts.RedactEnv, cmd.Start)
+	if err != nil {
+		return err
+	}
+	// Child shares the console and receives Ctrl+C itself
+	signal.Ignore(os.Interrupt)
+
+	_ = cmd.Wait()
+	audit(AuditFinish, res, cmd.ProcessState)
+	os.Exit(cmd.ProcessState.ExitCode())
+	return nil
+}
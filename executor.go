@@ -1,127 +1,602 @@
 package executor
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"regexp"
 	"time"
 )
 
 // Options respresents options to start process
 type Options struct {
-	Command    string                        // Command to run
-	Args       []string                      // Command arguments
-	Print      bool                          // Print output to console?
-	Capture    bool                          // Build buffer and capture output into Result.Output?
-	Wait       bool                          // Wait for program to finish?
-	Timeout    uint                          // Time in seconds allotted for the execution of the process before it get killed
-	Dir        string                        // Working directory
-	NewConsole bool                          // Spawn new console window on Windows?
-	Hide       bool                          // Try to hide process window on Windows?
-	OnChar     func(c string, p *os.Process) // Callback for each character from process StdOut and StdErr
-	OnLine     func(l string, p *os.Process) // Callback for each line from process StdOut and StdErr
+	Command               string                                         // Command to run
+	Args                  []string                                       // Command arguments
+	Print                 bool                                           // Print output to console?
+	Capture               bool                                           // Build buffer and capture output into Result.Output?
+	Wait                  bool                                           // Wait for program to finish?
+	Timeout               uint                                           // Time in seconds allotted for the execution of the process before it get killed with its descendants (Result.EndReason is EndTimeout then)
+	TimeoutDuration       time.Duration                                  // Time allotted for the execution of the process like Timeout, with sub-second precision (replaces Timeout if set)
+	Dir                   string                                         // Working directory
+	DirFunc               func() string                                  // Function returning working directory for each run, replacing Dir, like when options are reused by Factory or Load (see DatedDir)
+	NewConsole            bool                                           // Spawn new console window on Windows? Ignored on other platforms
+	Hide                  bool                                           // Try to hide process window on Windows? Ignored on other platforms
+	OnChar                func(c string, p *os.Process)                  // Callback for each character from process StdOut and StdErr
+	OnLine                func(l string, p *os.Process)                  // Callback for each line from process StdOut and StdErr
+	OnExit                func(ctx context.Context, res Result)          // Callback with Context (or context.Background()) and result, once process is waited for or did not start
+	EnvFiles              []string                                       // .env files to load into process environment (later files override earlier)
+	EnvSpec               *EnvSpec                                       // Sources to compose process environment from, instead of inheriting it (EnvFiles override it, if set)
+	Env                   []string                                       // Environment variables in "KEY=VALUE" form, overriding inherited ones, EnvSpec and EnvFiles
+	NoInheritEnv          bool                                           // Start with empty environment instead of environment of this process, if EnvSpec is not set?
+	CreateDir             bool                                           // Create working directory if it does not exist?
+	TempDir               bool                                           // Run in a new unique temporary directory (inside Dir, if set), removed after Wait?
+	CollectArtifacts      []string                                       // Glob patterns (relative to working directory) of files to enumerate into Result.Artifacts after Wait
+	MemoryLimit           uint64                                         // Resident memory size in bytes, exceeding which gets the process killed (0 = no limit)
+	CPUTimeLimit          uint                                           // CPU time in seconds allotted to the process before it get killed (0 = no limit)
+	IOPriority            IOPriority                                     // I/O scheduling priority (ionice class and level on Linux, background mode on Windows)
+	NoNetwork             bool                                           // Run in an empty network namespace on Linux? Not supported on other platforms (see Capabilities)
+	Sandbox               *Sandbox                                       // Run inside bubblewrap or firejail sandbox (nil = no sandbox)
+	RestrictToken         bool                                           // Run with restricted, low integrity token on Windows (AppContainer is not supported)?
+	UserSession           bool                                           // Run in session of logged on user with their token and environment, from service running as SYSTEM on Windows? Can't be combined with RestrictToken. Process gets the interactive desktop, and Stdin must be a file then
+	OnStats               func(s Stats)                                  // Callback for periodic resource usage samples of process and its descendants
+	StatsInterval         time.Duration                                  // Interval between OnStats samples (1 second if not set)
+	ReadRateLimit         int64                                          // Maximum rate in bytes per second of reading each of StdOut and StdErr (0 = no limit)
+	SlowCallbackThreshold time.Duration                                  // Report OnChar and OnLine callbacks blocking output reading for longer than this (0 = don't report)
+	OnSlowCallback        func(callback string, threshold time.Duration) // Called when a callback exceeds SlowCallbackThreshold, while it is still running (warning is printed to StdErr if not set)
+	MuxOutput             io.Writer                                      // Writer to send StdOut and StdErr into as Docker-style frames (see DemuxOutput)
+	RedactEnv             []string                                       // Additional parts of environment variable names to redact in Result.Trace (case-insensitive)
+	ExpandGlobs           bool                                           // Expand wildcard arguments (*, ? and [...]) into matching file paths?
+	GlobNoMatch           GlobNoMatch                                    // What to do with wildcard arguments matching no files
+	StdinFunc             func() ([]byte, error)                         // Function called repeatedly to produce StdIn content until it returns error (io.EOF when done), instead of inheriting StdIn
+	StdinRateLimit        int64                                          // Maximum rate in bytes per second of writing StdinFunc data (0 = no limit)
+	StdinLineDelay        time.Duration                                  // Delay after writing each line of StdinFunc data
+	StdinSource           StdinSource                                    // Where StdIn comes from, if StdinFunc and AutoAnswer are not set
+	Stdin                 io.Reader                                      // Reader to use as StdIn if StdinSource is StdinCustom
+	StdinClosing          StdinClosing                                   // When StdIn written with StdinFunc or AutoAnswer is closed
+	StdinCloseSignal      <-chan struct{}                                // Channel to close or send to, to close StdIn if StdinClosing is StdinCloseOnSignal
+	AutoAnswer            []AnswerRule                                   // Prompts to answer automatically by writing into StdIn (StdIn is not inherited if set)
+	ExpectedSHA256        string                                         // Hex encoded SHA-256 hash the resolved executable must have to be started
+	Verifier              func(path string) error                        // Function checking the resolved executable before start, returning error to refuse running it
+	RequireSignature      bool                                           // Refuse to start executable without valid Authenticode signature on Windows or code signature accepted by Gatekeeper on macOS?
+	SignerSubject         string                                         // Text the signer certificate name must contain, if RequireSignature is set
+	Context               context.Context                                // Context to kill the process on cancellation or deadline and to pass to callbacks (optional, see ProcessContext)
+	StderrTailSize        int                                            // Bytes of StdErr end to keep in Result.StderrTail (4 KB if not set, negative = don't keep)
+	SeparateCapture       bool                                           // Capture StdOut and StdErr separately into Result.Stdout and Result.Stderr?
+	Cache                 *Cache                                         // Cache to return results of the same previous runs from, if Wait is set (nil = no caching)
+	Backend               string                                         // Name of backend registered with RegisterBackend to run with ("" = local machine)
+	Encoding              CodePage                                       // Code page of output to decode into UTF-8, like CodePageUTF16LE (console output or OEM code page on Windows and UTF-8 elsewhere if not set)
+	Transforms            []Transform                                    // Transforms to apply to each of StdOut and StdErr in order, like DecodeCodePage, StripBOM, NormalizeNewlines and StripANSI (replace Encoding if set)
+	NormalizeNewlines     bool                                           // Convert CRLF and CR line endings into LF in captured output and OnLine callback (printed output is not changed)?
+	InvalidBytes          InvalidBytes                                   // What to do with bytes of output which are not valid characters after decoding
+	CaptureMemoryLimit    int64                                          // Bytes of captured output to keep in memory, the rest is written into Result.OutputFile, Result.StdoutFile and Result.StderrFile (0 = no limit)
+	pipeIn                *os.File                                       // Read end of pipe from the previous process of Pipeline, used as StdIn and closed after start
+	pipeOut               *os.File                                       // Write end of pipe to the next process of Pipeline, used as StdOut and closed after start
+	TeePiped              bool                                           // Read StdOut piped to the next process of Pipeline through executor, to also capture, print or pass it to callbacks?
+	TeeLimit              int64                                          // Bytes of piped StdOut to capture, print and pass to callbacks if TeePiped is set, the rest is only forwarded (0 = all)
+	onStarted             func(p *os.Process)                            // Called once process is started, for RunningPipeline to stop it
+	CoalesceInterval      time.Duration                                  // Pass text accumulated for this long to OnChar at once instead of each character (negative = text of each read, 0 = each character)
+	PrintBuffering        PrintBuffering                                 // How printed output is buffered before writing to console
+	PrintFlushInterval    time.Duration                                  // Interval of writing printed output if PrintBuffering is PrintIntervalFlushed (100 ms if not set)
+	TintStderr            bool                                           // Print StdErr in red? Captured output is not colored
+	StderrPrefix          string                                         // Text to print before each line of StdErr, like "! ". Captured output is not prefixed
+	groupPrinter          *groupPrinter                                  // Printer shared by processes of Group, printing whole lines with prefix
+	groupPrefix           string                                         // Prefix of printed lines in Group
+	Quiet                 bool                                           // Show status line with spinner instead of output and print captured output only if process fails?
+	StdoutEncoding        CodePage                                       // Code page of StdOut, if it differs from Encoding
+	StderrEncoding        CodePage                                       // Code page of StdErr, if it differs from Encoding
+	StdinEncoding         CodePage                                       // Code page to convert StdinFunc data and automatic answers into from UTF-8 (not converted if not set)
+	ForwardSignals        bool                                           // Relay interrupt and termination signals received by this process to the started one until it exits, if Wait is set?
+	DrainTimeout          time.Duration                                  // Time to keep reading output left after the process exited, if Wait is set, before giving up on it (0 = until streams are closed, which never happens if descendants keep them open)
+	ReadyWhen             ReadyCondition                                 // Condition of output line meaning the process is ready, to wait for before returning, if Wait is not set (see ReadyOnMatch)
+	ReadyAddress          string                                         // Address like "localhost:8080", opening of which means the process is ready, to wait for before returning, if Wait is not set
+	ReadyNetwork          string                                         // Network of ReadyAddress: "tcp" (accepting connections, default) or "udp" (bound on this machine)
+	ReadyTimeout          time.Duration                                  // Maximum time to wait for the process to be ready (0 = until it is ready or closes output, 30 seconds for ReadyAddress alone)
+	SampleHead            int                                            // Number of the first lines of StdOut and StdErr to keep in Result.Sample, without capturing the whole output
+	SampleTail            int                                            // Number of the last lines of StdOut and StdErr to keep in Result.Sample
+	SeverityRules         []SeverityRule                                 // Rules giving levels to lines of StdOut and StdErr, the first matching one wins (see DefaultSeverityRules)
+	OnSeverityLine        func(l string, sev Severity, p *os.Process)    // Callback for each line with its level by SeverityRules
+	FailIfOutputMatches   []*regexp.Regexp                               // Patterns of StdOut and StdErr lines meaning the process failed, even if it exits successfully
+	KillOnOutputMatch     bool                                           // Kill the process with its descendants once a line matches FailIfOutputMatches?
+	RequireOutputMatch    *regexp.Regexp                                 // Pattern of StdOut or StdErr line which must appear for the run to succeed, if Wait is set, like a marker of work done
+	Inherit               bool                                           // Connect StdOut and StdErr to the ones of this process directly, so the process sees the terminal (output is not printed by executor, captured or passed to callbacks)?
+	IgnoreBrokenPipe      bool                                           // Consider process killed by SIGPIPE (or exited with code 141) successful, like shells do when consumer exits early, as with "| head"? Not supported on Windows
+	Cleanup               bool                                           // Register process to kill with its descendants by Cleanup or CleanupAll, if Wait is not set?
+	LockKey               string                                         // Name of mutual-exclusion key, like "apt" or "git-repo:/path", to run one process holding it at a time (without Wait, only start is serialized)
 }
 
 // Result respresents process run result
 type Result struct {
-	DoneOk   bool   // Process exited successfully?
-	StartOk  bool   // Process started successfully?
-	ExitCode int    // Exit code
-	Output   string // Output of StdOut and StdErr
+	DoneOk               bool          // Process exited successfully?
+	StartOk              bool          // Process started successfully?
+	ExitCode             int           // Exit code
+	Output               string        // Output of StdOut and StdErr
+	Dir                  string        // Working directory the process was started in
+	Artifacts            []Artifact    // Files matching Options.CollectArtifacts
+	MemoryLimitExceeded  bool          // Process was killed for exceeding Options.MemoryLimit?
+	CPUTimeLimitExceeded bool          // Process was killed for exceeding Options.CPUTimeLimit?
+	PID                  int           // Process ID, use with Children() to inspect processes it spawned
+	StdoutBytes          int64         // Bytes read from StdOut
+	StderrBytes          int64         // Bytes read from StdErr
+	Trace                ExecTrace     // Record of what was executed
+	StdinBytes           int64         // Bytes written to StdIn by executor
+	EndReason            EndReason     // Why execution ended, if waited for
+	StderrTail           string        // Last bytes of StdErr, to explain failures without capturing the whole output
+	Stdout               string        // Output of StdOut, if Options.SeparateCapture is set
+	Stderr               string        // Output of StdErr, if Options.SeparateCapture is set
+	DecodeError          error         // Position of the first invalid byte of output, if Options.InvalidBytes is InvalidError
+	OutputFile           string        // Temporary file with captured output beyond Options.CaptureMemoryLimit, remove it when not needed (see OutputReader)
+	StdoutFile           string        // Temporary file with StdOut beyond Options.CaptureMemoryLimit, if Options.SeparateCapture is set (see StdoutReader)
+	StderrFile           string        // Temporary file with StdErr beyond Options.CaptureMemoryLimit, if Options.SeparateCapture is set (see StderrReader)
+	Ready                bool          // Process reported readiness with Options.ReadyWhen or opened Options.ReadyAddress?
+	Duration             time.Duration // Time from start to exit, if process was waited for
+	DrainCutShort        bool          // Output was still open after Options.DrainTimeout, so the rest of it was not read?
+	Sample               OutputSample  // The first and the last lines of output, if Options.SampleHead or Options.SampleTail is set
+	WarningLines         int           // Number of lines of SeverityWarning level by Options.SeverityRules
+	ErrorLines           int           // Number of lines of SeverityError level by Options.SeverityRules
+	FailedMatch          string        // The first line matching Options.FailIfOutputMatches, making the run failed
+	MissingMatch         bool          // No line matched Options.RequireOutputMatch, making the run failed?
+	BrokenPipe           bool          // Process was ended by writing into closed pipe, and considered successful as Options.IgnoreBrokenPipe is set?
+	Outcome              Outcome       // Overall status of execution
+	Err                  error         // Why process did not start or finish successfully (ExitError if it finished), nil if it succeeded or was not waited for
 }
 
 // Start starts a process
 func Start(opts Options) Result {
+	res := start(opts)
+	res.setOutcome(opts.Command)
+	if opts.OnExit != nil && (opts.Wait || !res.StartOk) {
+		ctx := opts.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		opts.OnExit(ctx, res)
+	}
+	return res
+}
+
+// start starts a process without setting Result.Outcome and calling Options.OnExit
+func start(opts Options) Result {
+	if opts.LockKey != "" {
+		defer lockKey(opts.LockKey)()
+		opts.LockKey = ""
+	}
+	if opts.pipeIn != nil || opts.pipeOut != nil {
+		// Neighbours in Pipeline get EOF only once pipe ends are closed, whichever way the process was started
+		defer opts.closePipeEnds()
+		if opts.Cache != nil || (opts.Backend != "" && opts.Backend != LocalBackendName) {
+			return startFailed(Result{ExitCode: -1}, fmt.Errorf("%w: Options.Cache or Options.Backend in Pipeline", ErrUnsupported))
+		}
+	}
+	if opts.Cache != nil && opts.Wait {
+		return opts.Cache.start(opts)
+	}
+	opts.Cache = nil
+	if GetVerbosity() < VerbosityNormal {
+		opts.Print = false
+	}
+	if opts.Quiet {
+		return startQuiet(opts)
+	}
+	return startBackend(opts)
+}
+
+// startLocal starts a process on the local machine
+func startLocal(opts Options) Result {
+	// StdOut piped to the next process is copied through executor, rather than given to the process
+	var teeOut *os.File
+	if opts.pipeOut != nil && opts.TeePiped && !opts.Inherit && !opts.NewConsole && !opts.Hide {
+		teeOut, opts.pipeOut = opts.pipeOut, nil
+	}
+	defer opts.closePipeEnds()
+
 	res := Result{
 		ExitCode: -1,
 	}
 
-	var outSb strings.Builder
+	// Refuse options which can't be honored on this platform
+	if err := checkSupported(opts); err != nil {
+		return startFailed(res, err)
+	}
+
+	var scanner *outputScanner
 	var err error
 
 	// Create context for command (empty or with timeout)
-	ctx := context.Background()
-	var cancel context.CancelFunc
-	if opts.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(opts.Timeout)*time.Second)
-		defer cancel()
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Process is killed on cancellation by exec.Cmd, but on timeout by executor, to find its descendants first
+	execCtx := ctx
+	timeout := opts.TimeoutDuration
+	if timeout <= 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+	// Timer of process running in background is stopped once it finished or did not start
+	var stopTimeout context.CancelFunc
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		if opts.Wait {
+			defer cancel()
+		} else {
+			stopTimeout = cancel
+			defer func() {
+				if !res.StartOk {
+					stopTimeout()
+				}
+			}()
+		}
+	}
+
+	// Set working directory
+	dir, cleanupDir, err := prepareDir(opts)
+	if err != nil {
+		return startFailed(res, err)
+	}
+	// Temporary directory of running process is left in place when not waiting for it
+	defer func() {
+		if opts.Wait || !res.StartOk {
+			cleanupDir()
+		}
+	}()
+	res.Dir = dir
+
+	// Expand wildcards
+	if opts.ExpandGlobs {
+		opts.Args, err = expandGlobs(opts.Args, dir, opts.GlobNoMatch)
+		if err != nil {
+			return startFailed(res, err)
+		}
+	}
+
+	// Ask policy
+	if err := checkPolicy(opts.Command, opts.Args, dir); err != nil {
+		return startFailed(res, err)
+	}
+
+	// Verify executable
+	if err := verifyExecutable(opts, dir); err != nil {
+		return startFailed(res, err)
+	}
+
+	// Limit CPU time from the start where the limit is inherited, so children forked early can't escape it
+	command, args := opts.Command, opts.Args
+	if opts.CPUTimeLimit > 0 {
+		command, args, err = cpuTimeLimitCommand(command, args, dir, opts.CPUTimeLimit)
+		if err != nil {
+			return startFailed(res, err)
+		}
+	}
+
+	// Wrap command with sandbox tool
+	if opts.Sandbox != nil {
+		sandboxed, sandboxedArgs, err := opts.Sandbox.wrap(command, args, dir)
+		if err == nil {
+			command, args = sandboxed, sandboxedArgs
+		} else if opts.Sandbox.AllowUnsandboxed && errors.Is(err, ErrNoSandboxTool) {
+			diagf("%v, starting without sandbox\n", err)
+		} else {
+			return startFailed(res, err)
+		}
 	}
 
 	// Create command
-	cmd := exec.CommandContext(ctx, opts.Command, opts.Args...)
+	cmd := exec.CommandContext(execCtx, normalizeCommandPath(command), args...)
+	cmd.Dir = normalizeDir(dir)
+
+	// Compose process environment
+	cmd.Env, err = processEnv(opts)
+	if err != nil {
+		return startFailed(res, err)
+	}
 
-	cmd.Dir = opts.Dir
-	// Fix "ERROR: Input redirection is not supported, exiting the process immediately" on Windows
-	cmd.Stdin = os.Stdin
+	// Pipes are closed here if the process did not start or was waited for, otherwise by their readers and writers
+	var stdinPipe, stdoutPipe, stderrPipe *trackedPipe
+	// Ends of pipes created by executor and given to the process, closed once they are inherited by it
+	var stdinReadEnd, stdoutWriteEnd, stderrWriteEnd *trackedPipe
+	defer func() {
+		if !res.StartOk || opts.Wait {
+			closePipes(stdinPipe, stdoutPipe, stderrPipe, stdinReadEnd, stdoutWriteEnd, stderrWriteEnd)
+		}
+		if !res.StartOk && teeOut != nil && teeOut.Close() == nil {
+			pipesOpened(-1)
+		}
+	}()
 
-	if opts.NewConsole || opts.Hide {
+	var stdin *stdinWriter
+	var ready *readyWaiter
+	var lines *lineSampler
+	var classifier *lineClassifier
+	var failMatch *failMatcher
+	var requireMatch *requireMatcher
+	if opts.StdinFunc != nil || len(opts.AutoAnswer) > 0 {
+		stdinPipe, stdinReadEnd, err = inputPipe(cmd, opts.UserSession)
+		if err != nil {
+			return startFailed(res, err)
+		}
+		stdin = newStdinWriter(stdinPipe)
+		stdin.rate = opts.StdinRateLimit
+		stdin.lineDelay = opts.StdinLineDelay
+		stdin.closing = opts.StdinClosing
+		if opts.StdinEncoding != 0 {
+			encode, err := newEncoder(opts.StdinEncoding)
+			if err != nil {
+				return startFailed(res, err)
+			}
+			stdin.encode = encode
+		}
+	} else if opts.pipeIn != nil {
+		cmd.Stdin = opts.pipeIn
+	} else {
+		switch opts.StdinSource {
+		case StdinNull:
+			// Null device is opened by exec.Cmd
+			cmd.Stdin = nil
+		case StdinCustom:
+			cmd.Stdin = opts.Stdin
+		default:
+			// Fix "ERROR: Input redirection is not supported, exiting the process immediately" on Windows
+			cmd.Stdin = os.Stdin
+		}
+	}
+
+	if opts.Inherit {
+		// Interactive programs detect terminal and draw their interface as if started from shell
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if opts.pipeOut != nil {
+			cmd.Stdout = opts.pipeOut
+		}
+	} else if opts.NewConsole || opts.Hide {
 		setCmdAttr(cmd, opts.NewConsole, opts.Hide)
 
 		cmd.Stderr = os.Stderr
 		cmd.Stdout = os.Stdout
+		if opts.pipeOut != nil {
+			cmd.Stdout = opts.pipeOut
+		}
 	} else { // Can capture output
-		stdoutReader, err := cmd.StdoutPipe()
-
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return res
+		// Lines are checked for readiness and failures, sampled and classified along with the line callback
+		if opts.ReadyWhen != nil {
+			ready = newReadyWaiter()
+			opts.OnLine = ready.wrapOnLine(opts.ReadyWhen, opts.OnLine)
+		}
+		if opts.SampleHead > 0 || opts.SampleTail > 0 {
+			lines = newLineSampler(opts.SampleHead, opts.SampleTail)
+			opts.OnLine = lines.wrapOnLine(opts.OnLine)
+		}
+		if len(opts.SeverityRules) > 0 || opts.OnSeverityLine != nil {
+			classifier = newLineClassifier(opts.SeverityRules)
+			opts.OnLine = classifier.wrapOnLine(opts.OnSeverityLine, opts.OnLine)
+		}
+		if len(opts.FailIfOutputMatches) > 0 {
+			failMatch = newFailMatcher(opts.FailIfOutputMatches, opts.KillOnOutputMatch)
+			opts.OnLine = failMatch.wrapOnLine(opts.OnLine)
+		}
+		if opts.RequireOutputMatch != nil {
+			requireMatch = &requireMatcher{pattern: opts.RequireOutputMatch}
+			opts.OnLine = requireMatch.wrapOnLine(opts.OnLine)
 		}
 
-		// Redirect StdErr to StdOut
-		cmd.Stderr = cmd.Stdout
+		// Streams nothing consumes are connected directly, to the console or to the null device
+		scanned := outputScanned(opts)
+		var stdoutReader, stderrReader io.Reader
+		switch {
+		case opts.pipeOut != nil:
+			// Output goes straight to the next process of pipeline, without copying through this one
+			cmd.Stdout = opts.pipeOut
+		case scanned || teeOut != nil:
+			stdoutPipe, stdoutWriteEnd, err = outputPipe(cmd, StreamStdout, opts.DrainTimeout > 0 && opts.Wait || opts.UserSession)
+			if err != nil {
+				return startFailed(res, err)
+			}
+			stdoutReader = stdoutPipe
+			if teeOut != nil {
+				stdoutReader = newPipeTee(stdoutPipe, teeOut, opts.TeeLimit)
+			}
+		case opts.Print:
+			cmd.Stdout = os.Stdout
+		}
 
-		stdoutScanner := bufio.NewScanner(stdoutReader)
-		stdoutScanner.Split(bufio.ScanRunes)
-		var lineSb strings.Builder
+		if scanned || opts.StderrTailSize >= 0 {
+			stderrPipe, stderrWriteEnd, err = outputPipe(cmd, StreamStderr, opts.DrainTimeout > 0 && opts.Wait || opts.UserSession)
+			if err != nil {
+				return startFailed(res, err)
+			}
+			stderrReader = stderrPipe
+		} else if opts.Print {
+			cmd.Stderr = os.Stderr
+		}
 
-		// Scan output
-		go func() {
-			for stdoutScanner.Scan() {
-				char := stdoutScanner.Text()
-				if opts.Print {
-					fmt.Print(char)
-				}
-				if opts.Capture {
-					outSb.WriteString(char)
-				}
-				// Char callback
-				if opts.OnChar != nil {
-					opts.OnChar(char, cmd.Process)
-				}
-				// Build the line
-				if opts.OnLine != nil {
-					if char != "\n" && char != "\r" {
-						lineSb.WriteString(char)
-					} else {
-						// Line callback
-						opts.OnLine(lineSb.String(), cmd.Process)
-						lineSb.Reset()
-					}
-				}
+		if stdoutReader != nil || stderrReader != nil {
+			scanner = newOutputScanner(opts, cmd, stdoutReader, stderrReader)
+			if len(opts.AutoAnswer) > 0 {
+				scanner.answerer = newAutoAnswerer(opts.AutoAnswer, stdin)
 			}
-		}()
+		}
+	}
+
+	// Isolate from network
+	if opts.NoNetwork {
+		if err := isolateNetwork(cmd); err != nil {
+			diagln(err)
+		}
 	}
 
-	// Start the command
-	err = cmd.Start()
+	// Drop privileges
+	if opts.UserSession && opts.RestrictToken {
+		return startFailed(res, errors.New("user session and restricted token can't be combined"))
+	}
+	if opts.RestrictToken {
+		closeToken, err := restrictToken(cmd)
+		if err != nil {
+			return startFailed(res, err)
+		}
+		defer closeToken()
+	}
+
+	// Start the command, in session of logged on user if asked
+	res.Trace = newExecTrace(cmd, opts.RedactEnv)
+	debugTrace(res.Trace)
+	if opts.UserSession {
+		err = startInUserSession(execCtx, cmd)
+	} else {
+		err = cmd.Start()
+	}
+	// Pipe ends belong to the child now, so it gets EOF or SIGPIPE once its neighbour exits
+	opts.closePipeEnds()
+	closePipes(stdinReadEnd, stdoutWriteEnd, stderrWriteEnd)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		diagln(err)
+		res.Err = err
+		audit(AuditFinish, res, nil)
+		recordHistory(opts, res)
 		return res
 	}
 	res.StartOk = true
+	res.PID = cmd.Process.Pid
+	audit(AuditStart, res, nil)
+
+	if opts.onStarted != nil {
+		opts.onStarted(cmd.Process)
+	}
+
+	// Remember to kill
+	if opts.Cleanup && !opts.Wait {
+		registerCleanup(cmd.Process)
+	}
+
+	// Kill on timeout along with descendants, as they may keep output open and Wait blocked
+	if timeout > 0 {
+		proc := cmd.Process
+		var waited <-chan struct{}
+		if opts.Wait {
+			ch := make(chan struct{})
+			defer close(ch)
+			waited = ch
+		} else {
+			// Process running in background may close its output long before it exits
+			waited = processExited(proc)
+		}
+		goTracked(func() {
+			select {
+			case <-ctx.Done():
+				if ctx.Err() == context.DeadlineExceeded {
+					killWithDescendants(proc)
+				}
+			case <-waited:
+			}
+			if stopTimeout != nil {
+				stopTimeout()
+			}
+		})
+	}
+
+	// Relay signals
+	if opts.ForwardSignals && opts.Wait {
+		defer sharedSignalForwarder.add(cmd.Process)()
+	}
+
+	// Scan output
+	if scanner != nil && opts.Context != nil {
+		setProcessContext(cmd.Process, opts.Context, scanner.finished())
+	}
+	scanner.start()
+
+	// Feed input
+	if opts.StdinFunc != nil {
+		goTracked(func() { stdin.feed(opts.StdinFunc) })
+	}
+	if stdin != nil && opts.StdinClosing == StdinCloseOnSignal && opts.StdinCloseSignal != nil {
+		// Watching stops once the process is waited for, otherwise it lasts until the signal
+		var stopClosing chan struct{}
+		if opts.Wait {
+			stopClosing = make(chan struct{})
+			defer close(stopClosing)
+		}
+		goTracked(func() { stdin.closeOn(opts.StdinCloseSignal, stopClosing) })
+	}
+
+	// Watch memory usage
+	var watchdog *memoryWatchdog
+	if opts.MemoryLimit > 0 {
+		watchdog = startMemoryWatchdog(cmd.Process, opts.MemoryLimit, ctx.Done())
+	}
+
+	// Sample resource usage
+	var sampler *statsSampler
+	if opts.OnStats != nil {
+		sampler = startStatsSampler(cmd.Process, opts.StatsInterval, opts.OnStats)
+	}
+
+	// Set I/O priority
+	if err := setIOPriority(cmd.Process, opts.IOPriority); err != nil {
+		diagln(err)
+	}
+
+	// Limit CPU time
+	var cpuLimiter *cpuTimeLimiter
+	if opts.CPUTimeLimit > 0 {
+		if cpuLimiter, err = limitCPUTime(cmd.Process, opts.CPUTimeLimit); err != nil {
+			diagln(err)
+		}
+	}
+
+	// Wait for readiness
+	if opts.ReadyAddress != "" && ready == nil {
+		ready = newReadyWaiter()
+	}
+	if ready != nil && !opts.Wait {
+		exited, timeout := scanner.finished(), opts.ReadyTimeout
+		stop := make(chan struct{})
+		if opts.ReadyAddress != "" {
+			goTracked(func() { ready.probe(opts.ReadyNetwork, opts.ReadyAddress, stop) })
+			// Output may be not read at all, so exit of process is not noticed and probing stops on timeout instead
+			if opts.ReadyWhen == nil {
+				exited = nil
+				if timeout == 0 {
+					timeout = defaultReadyAddressTimeout
+				}
+			}
+		}
+		res.Ready = ready.wait(timeout, exited)
+		close(stop)
+	}
 
 	// Wait for the command to finish execution
 	if opts.Wait {
-		err = cmd.Wait()
+		if opts.DrainTimeout > 0 {
+			// Pipes are not closed by Wait, so output left after exit is read for limited time
+			err = cmd.Wait()
+			res.DrainCutShort = !scanner.waitFor(opts.DrainTimeout)
+		} else {
+			// Pipes must be read to the end before Wait closes them
+			scanner.wait()
+			err = cmd.Wait()
+		}
+		res.Duration = time.Since(res.Trace.StartTime)
+		watchdog.stop()
+		sampler.stop()
+		if watchdog.limitExceeded() {
+			res.MemoryLimitExceeded = true
+			diagln(ErrMemoryLimit)
+		}
+		if cpuLimiter.limitExceeded(cmd.ProcessState) {
+			res.CPUTimeLimitExceeded = true
+			diagln(ErrCPUTimeLimit)
+		}
+		res.EndReason = endReason(res, cmd.ProcessState, ctx)
+		audit(AuditFinish, res, cmd.ProcessState)
+		// Output captured before the process was killed is still returned
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "\n%v\n", err)
+			diagf("\n%v\n", err)
 			if ctx.Err() != nil {
-				fmt.Fprintln(os.Stderr, ctx.Err())
+				diagln(ctx.Err())
 			}
-			return res
 		}
 	}
 
@@ -129,8 +604,29 @@ func Start(opts Options) Result {
 	if cmd.ProcessState != nil {
 		res.DoneOk = cmd.ProcessState.Success()
 		res.ExitCode = cmd.ProcessState.ExitCode()
+		if !res.DoneOk && opts.IgnoreBrokenPipe && brokenPipe(cmd.ProcessState) {
+			res.DoneOk = true
+			res.BrokenPipe = true
+		}
+	}
+	scanner.fill(&res)
+	res.Sample = lines.sample()
+	classifier.fill(&res)
+	failMatch.fill(&res)
+	requireMatch.fill(&res)
+	if opts.Wait {
+		res.Ready = ready.isReady()
+	}
+	res.StdinBytes = stdin.bytesWritten()
+
+	// Enumerate produced files
+	if opts.Wait && len(opts.CollectArtifacts) > 0 {
+		res.Artifacts, err = collectArtifacts(dir, opts.CollectArtifacts)
+		if err != nil {
+			diagln(err)
+		}
 	}
-	res.Output = outSb.String()
 
+	recordHistory(opts, res)
 	return res
 }
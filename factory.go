@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"regexp"
+)
+
+// Middleware wraps function starting processes, to change options or results of every process started by Factory.
+// Values of execution context, like request ID, are available from Options.Context
+type Middleware func(next func(opts Options) Result) func(opts Options) Result
+
+// Factory represents options shared by many processes, like working directory, encoding and callbacks,
+// so they are not repeated at every call site
+type Factory struct {
+	defaults Options
+	start    func(opts Options) Result
+}
+
+// NewFactory returns Factory of processes with <defaults>, started through <middleware>.
+// The first middleware is the outermost one, so it sees options before and results after all others
+func NewFactory(defaults Options, middleware ...Middleware) *Factory {
+	start := Start
+	for i := len(middleware) - 1; i >= 0; i-- {
+		start = middleware[i](start)
+	}
+	return &Factory{defaults: defaults, start: start}
+}
+
+// Command returns default options of Factory with <command> and <args>, to adjust before passing them to Start.
+// Slices of the result can be modified without affecting the defaults
+func (f *Factory) Command(command string, args ...string) Options {
+	opts := f.defaults
+	opts.Command = command
+	opts.Args = append([]string(nil), args...)
+	opts.EnvFiles = append([]string(nil), f.defaults.EnvFiles...)
+	opts.Env = append([]string(nil), f.defaults.Env...)
+	opts.CollectArtifacts = append([]string(nil), f.defaults.CollectArtifacts...)
+	opts.RedactEnv = append([]string(nil), f.defaults.RedactEnv...)
+	opts.AutoAnswer = append([]AnswerRule(nil), f.defaults.AutoAnswer...)
+	opts.Transforms = append([]Transform(nil), f.defaults.Transforms...)
+	opts.SeverityRules = append([]SeverityRule(nil), f.defaults.SeverityRules...)
+	opts.FailIfOutputMatches = append([]*regexp.Regexp(nil), f.defaults.FailIfOutputMatches...)
+	return opts
+}
+
+// Start starts a process with <opts> through middleware of Factory
+func (f *Factory) Start(opts Options) Result {
+	return f.start(opts)
+}
+
+// Run starts a process of <command> with <args> and default options of Factory
+func (f *Factory) Run(command string, args ...string) Result {
+	return f.Start(f.Command(command, args...))
+}
@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"fmt"
+)
+
+// FlakyReport represents divergence between repeated runs of the same process
+type FlakyReport struct {
+	Flaky     bool           // Any run differs from the first one in exit code or output?
+	Results   []Result       // Results of runs, in order of running
+	ExitCodes map[int]int    // Number of runs by exit code
+	Diffs     map[int]string // Unified diffs of output of the first run and each differing run, by run index
+}
+
+// DetectFlaky runs a process described by <opts> <runs> times one by one and compares exit code and output of each run
+// with the first one. Output is always captured and process is always waited for
+func DetectFlaky(opts Options, runs int) (FlakyReport, error) {
+	report := FlakyReport{ExitCodes: map[int]int{}, Diffs: map[int]string{}}
+	if runs < 2 {
+		return report, fmt.Errorf("at least 2 runs are needed to detect flakiness, got %v", runs)
+	}
+	opts.Wait = true
+	opts.Capture = true
+
+	for i := 0; i < runs; i++ {
+		res := Start(opts)
+		if !res.StartOk {
+			return report, res.Err
+		}
+		report.Results = append(report.Results, res)
+		report.ExitCodes[res.ExitCode]++
+
+		first := report.Results[0]
+		if i == 0 {
+			continue
+		}
+		if res.ExitCode != first.ExitCode {
+			report.Flaky = true
+		}
+		if diff := unifiedDiff("run 1", fmt.Sprintf("run %v", i+1), first.Output, res.Output); diff != "" {
+			report.Flaky = true
+			report.Diffs[i] = diff
+		}
+	}
+	return report, nil
+}
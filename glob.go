@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// GlobNoMatch represents what to do with a wildcard argument matching no files
+type GlobNoMatch int
+
+const (
+	GlobKeep   GlobNoMatch = iota // Pass the pattern as is, like POSIX shells do by default
+	GlobRemove                    // Remove the argument, like bash with nullglob
+	GlobFail                      // Refuse to start, like bash with failglob
+)
+
+// expandGlobs returns <args> with wildcard arguments replaced by sorted paths of matching files.
+// Relative patterns are matched against <dir>, resulting paths stay relative
+func expandGlobs(args []string, dir string, noMatch GlobNoMatch) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		pattern := arg
+		if dir != "" && !filepath.IsAbs(arg) {
+			pattern = filepath.Join(dir, arg)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", arg, err)
+		}
+
+		if len(matches) == 0 {
+			switch noMatch {
+			case GlobKeep:
+				expanded = append(expanded, arg)
+			case GlobFail:
+				return nil, fmt.Errorf("no matches found: %v", arg)
+			}
+			continue
+		}
+
+		for _, match := range matches {
+			if dir != "" && !filepath.IsAbs(arg) {
+				if rel, err := filepath.Rel(dir, match); err == nil {
+					match = rel
+				}
+			}
+			expanded = append(expanded, match)
+		}
+	}
+	return expanded, nil
+}
@@ -2,4 +2,7 @@ module github.com/SCP002/executor
 
 go 1.16
 
-require golang.org/x/sys v0.0.0-20210511113859-b0526f3d8744
+require (
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/sys v0.0.0-20210511113859-b0526f3d8744
+)
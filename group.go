@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// groupColors are ANSI colors of line prefixes of Group processes. Red is left for TintStderr
+var groupColors = []string{"\x1b[36m", "\x1b[33m", "\x1b[32m", "\x1b[35m", "\x1b[34m", "\x1b[96m", "\x1b[93m", "\x1b[92m"}
+
+// Group represents processes running concurrently.
+//
+// Printed output of processes is written line by line, so lines of different processes never interleave.
+// Each line starts with name of its process, in color distinct from neighbours
+type Group struct {
+	Commands    []Options // Options of processes
+	Names       []string  // Names to prefix lines of processes with, in order of Commands (command names if not set)
+	Concurrency int       // Maximum number of processes running at the same time (0 = all at once)
+	NoColor     bool      // Don't color line prefixes?
+}
+
+// groupPrinter serializes printed lines of Group processes
+type groupPrinter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// writeLine prints <line> with <prefix>
+func (g *groupPrinter) writeLine(prefix string, line []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, _ = io.WriteString(g.w, prefix)
+	_, _ = g.w.Write(line)
+}
+
+// Run starts processes, waits for them to finish and returns their results in order of Commands
+func (g Group) Run() []Result {
+	results := make([]Result, len(g.Commands))
+	printer := &groupPrinter{w: os.Stdout}
+	if !g.NoColor {
+		enableVirtualTerminal()
+	}
+
+	names := make([]string, len(g.Commands))
+	width := 0
+	for i, opts := range g.Commands {
+		if i < len(g.Names) && g.Names[i] != "" {
+			names[i] = g.Names[i]
+		} else {
+			names[i] = strings.TrimSuffix(filepath.Base(opts.Command), filepath.Ext(opts.Command))
+		}
+		if len(names[i]) > width {
+			width = len(names[i])
+		}
+	}
+
+	concurrency := g.Concurrency
+	if concurrency <= 0 || concurrency > len(g.Commands) {
+		concurrency = len(g.Commands)
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range g.Commands {
+		opts := g.Commands[i]
+		opts.Wait = true
+		opts.groupPrinter = printer
+		opts.groupPrefix = fmt.Sprintf("%-*v | ", width, names[i])
+		if !g.NoColor {
+			opts.groupPrefix = groupColors[i%len(groupColors)] + opts.groupPrefix + ansiReset
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, opts Options) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = Start(opts)
+		}(i, opts)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandoffFilesEnv is an environment variable listing file descriptors of files handed off to the replacement process
+const HandoffFilesEnv = "EXECUTOR_HANDOFF_FDS"
+
+// defaultHandoffTimeout is a time allotted for replacement process to become ready if HandoffOptions.Timeout is not set
+const defaultHandoffTimeout = 30 * time.Second
+
+// ErrNotReady is reported when replacement process did not signal readiness in time
+var ErrNotReady = errors.New("replacement process did not become ready")
+
+// HandoffOptions represents options to hand off work to a replacement process
+type HandoffOptions struct {
+	Command      string         // Command to run (current executable if empty)
+	Args         []string       // Command arguments
+	Env          []string       // Additional environment variables in "KEY=VALUE" form
+	Files        []*os.File     // Open files (e.g. listening sockets) to pass as file descriptors 3, 4, ... (not supported on Windows)
+	ReadyPattern *regexp.Regexp // Line in StdOut of replacement signaling it is ready (line "READY" if nil)
+	Timeout      time.Duration  // Time allotted for replacement to become ready (30 seconds if not set)
+	Rollback     func()         // Called after killing replacement which failed to become ready, e.g. to restore previous binary
+}
+
+// Handoff starts a replacement process in a new session and waits until it prints the readiness line to StdOut.
+// On success, the caller is expected to exit, leaving the work to the replacement.
+// If replacement exits or does not become ready in time, it is killed, Rollback is called and error is returned.
+//
+// Handed off files are listed in HandoffFilesEnv of replacement. After signaling readiness, replacement should not
+// write to StdOut anymore, as it is read by the exiting parent. StdErr is inherited.
+// Replacement denied by the global policy is never started, so Rollback is not called for it
+func Handoff(opts HandoffOptions) (*os.Process, error) {
+	command := opts.Command
+	if command == "" {
+		path, err := SelfPath()
+		if err != nil {
+			return nil, err
+		}
+		command = path
+	}
+	pattern := opts.ReadyPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(`^READY$`)
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultHandoffTimeout
+	}
+	if err := checkExecution(Options{Command: command, Args: opts.Args}, ""); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(command, opts.Args...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	if len(opts.Files) > 0 {
+		fds := make([]string, len(opts.Files))
+		for i := range opts.Files {
+			fds[i] = strconv.Itoa(3 + i)
+		}
+		cmd.Env = append(cmd.Env, HandoffFilesEnv+"="+strings.Join(fds, ","))
+		cmd.ExtraFiles = opts.Files
+	}
+	cmd.Stderr = os.Stderr
+	setNewSession(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := startAudited(cmd, nil, cmd.Start); err != nil {
+		return nil, err
+	}
+
+	ready := make(chan bool, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if pattern.MatchString(strings.TrimRight(scanner.Text(), "\r")) {
+				ready <- true
+				return
+			}
+		}
+		ready <- false
+	}()
+
+	select {
+	case ok := <-ready:
+		if ok {
+			return cmd.Process, nil
+		}
+		err = fmt.Errorf("%w: process exited", ErrNotReady)
+	case <-time.After(timeout):
+		err = fmt.Errorf("%w in %v", ErrNotReady, timeout)
+	}
+
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	if opts.Rollback != nil {
+		opts.Rollback()
+	}
+	return nil, err
+}
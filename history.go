@@ -0,0 +1,286 @@
+package executor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// errHistoryClosed is reported on use of closed History
+var errHistoryClosed = errors.New("history is closed")
+
+// defaultHistoryMaxOutput is the number of output bytes kept in HistoryRecord if OpenHistory got zero limit
+const defaultHistoryMaxOutput = 16 * 1024
+
+// HistoryRecord represents a recorded execution
+type HistoryRecord struct {
+	ID           int64         // Sequential record ID, starting with 1
+	Command      string        // Options.Command
+	Args         []string      // Options.Args
+	Dir          string        // Working directory the process was started in (Result.Dir)
+	EnvFiles     []string      // Options.EnvFiles
+	EnvSpec      *EnvSpec      // Options.EnvSpec, without Computed functions
+	Env          []string      // Options.Env
+	NoInheritEnv bool          // Options.NoInheritEnv
+	Trace        ExecTrace     // Record of what was executed
+	Duration     time.Duration // Time from start to exit, if process was waited for
+	StartOk      bool          // Process started successfully?
+	DoneOk       bool          // Process exited successfully?
+	ExitCode     int           // Exit code
+	EndReason    EndReason     // Why execution ended
+	Output       string        // End of captured output, truncated to History limit
+}
+
+// HistoryQuery represents filter of history records. Zero fields match any record
+type HistoryQuery struct {
+	Command  string    // Part of command to match
+	Since    time.Time // Earliest start time
+	Until    time.Time // Latest start time
+	ExitCode *int      // Exit code to match
+	Limit    int       // Maximum number of records to return, the newest ones are kept
+}
+
+// History is a persistent store of executions in a Bolt database file.
+//
+// The file can be shared by several processes: it is opened for each operation only, as Bolt locks it while open,
+// and record IDs come from sequence of the database, so they stay sequential and never collide.
+// Records are read from the file when requested, so memory use does not grow with history
+type History struct {
+	mu        sync.Mutex
+	path      string
+	maxOutput int
+	closed    bool
+}
+
+// historyBucket is a Bolt bucket of history records, keyed by big-endian ID
+var historyBucket = []byte("records")
+
+// historyLockTimeout is a maximum time to wait for other process to release history file
+const historyLockTimeout = 10 * time.Second
+
+var (
+	historyMu sync.RWMutex
+	history   *History
+)
+
+// OpenHistory opens history file at <path>, creating it if it does not exist.
+// Captured output of new records is truncated to the last <maxOutput> bytes (16 KB if 0, negative = don't keep)
+func OpenHistory(path string, maxOutput int) (*History, error) {
+	if maxOutput == 0 {
+		maxOutput = defaultHistoryMaxOutput
+	}
+	h := &History{path: path, maxOutput: maxOutput}
+	err := h.update(func(b *bolt.Bucket) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// update runs <f> with records bucket in read-write transaction. Must be called with mutex locked
+func (h *History) update(f func(b *bolt.Bucket) error) error {
+	if h.closed {
+		return errHistoryClosed
+	}
+	db, err := bolt.Open(h.path, 0600, &bolt.Options{Timeout: historyLockTimeout})
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(historyBucket)
+		if err != nil {
+			return err
+		}
+		return f(b)
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// view runs <f> with records bucket in read-only transaction. Must be called with mutex locked
+func (h *History) view(f func(b *bolt.Bucket) error) error {
+	if h.closed {
+		return errHistoryClosed
+	}
+	db, err := bolt.Open(h.path, 0600, &bolt.Options{Timeout: historyLockTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket)
+		if b == nil {
+			return fmt.Errorf("%v: no history records", h.path)
+		}
+		return f(b)
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SetHistory sets global history recording all processes started with Start. Nil disables recording
+func SetHistory(h *History) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	history = h
+}
+
+// recordHistory records run of process described by <opts> with <res> to the global history, if set
+func recordHistory(opts Options, res Result) {
+	historyMu.RLock()
+	h := history
+	historyMu.RUnlock()
+	if h == nil {
+		return
+	}
+	if err := h.add(opts, res); err != nil {
+		diagln(err)
+	}
+}
+
+// add appends record of process described by <opts> with <res>
+func (h *History) add(opts Options, res Result) error {
+	rec := HistoryRecord{
+		Command:      opts.Command,
+		Args:         opts.Args,
+		Dir:          res.Dir,
+		EnvFiles:     opts.EnvFiles,
+		Env:          opts.Env,
+		NoInheritEnv: opts.NoInheritEnv,
+		Trace:        res.Trace,
+		StartOk:      res.StartOk,
+		DoneOk:       res.DoneOk,
+		ExitCode:     res.ExitCode,
+		EndReason:    res.EndReason,
+	}
+	if opts.EnvSpec != nil {
+		// Functions can't be stored
+		spec := *opts.EnvSpec
+		spec.Computed = nil
+		rec.EnvSpec = &spec
+	}
+	if opts.Wait && res.StartOk {
+		rec.Duration = res.Duration
+	}
+	output := res.Output
+	if output == "" {
+		output = res.Stdout + res.Stderr
+	}
+	if h.maxOutput > 0 {
+		rec.Output = truncateHead(output, h.maxOutput)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.update(func(b *bolt.Bucket) error {
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		rec.ID = int64(id)
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return b.Put(historyKey(rec.ID), data)
+	})
+}
+
+// Get returns record with <id>
+func (h *History) Get(id int64) (HistoryRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var rec HistoryRecord
+	found := false
+	err := h.view(func(b *bolt.Bucket) error {
+		data := b.Get(historyKey(id))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &rec)
+	})
+	if err != nil {
+		diagln(err)
+		return HistoryRecord{}, false
+	}
+	return rec, found
+}
+
+// Query returns records matching <q>, oldest first
+func (h *History) Query(q HistoryQuery) []HistoryRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var found []HistoryRecord
+	// Records are visited newest first, so reading stops once Limit is reached
+	err := h.view(func(b *bolt.Bucket) error {
+		c := b.Cursor()
+		for k, data := c.Last(); k != nil; k, data = c.Prev() {
+			var rec HistoryRecord
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return fmt.Errorf("%v: record %v: %v", h.path, binary.BigEndian.Uint64(k), err)
+			}
+			if q.Command != "" && !strings.Contains(rec.Command, q.Command) {
+				continue
+			}
+			if !q.Since.IsZero() && rec.Trace.StartTime.Before(q.Since) {
+				continue
+			}
+			if !q.Until.IsZero() && rec.Trace.StartTime.After(q.Until) {
+				continue
+			}
+			if q.ExitCode != nil && rec.ExitCode != *q.ExitCode {
+				continue
+			}
+			found = append(found, rec)
+			if q.Limit > 0 && len(found) == q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		diagln(err)
+	}
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found
+}
+
+// Close stops using the history file. Records can't be added or read afterwards
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	return nil
+}
+
+// historyKey returns key of record with <id>, ordered as IDs are
+func historyKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+// truncateHead returns last <n> bytes of <s>, without a character cut in half at the beginning
+func truncateHead(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	for i := 0; i < utf8.UTFMax && len(s) > 0 && !utf8.RuneStart(s[0]); i++ {
+		s = s[1:]
+	}
+	return s
+}
@@ -0,0 +1,17 @@
+package executor
+
+// IOClass represents I/O scheduling class
+type IOClass int
+
+const (
+	IOClassNone       IOClass = iota // Leave I/O priority unchanged
+	IOClassRealtime                  // Realtime class (Linux only, requires privileges)
+	IOClassBestEffort                // Best-effort class with priority level
+	IOClassIdle                      // Idle class, process gets disk time only when nobody else needs it
+)
+
+// IOPriority represents I/O scheduling priority of process
+type IOPriority struct {
+	Class IOClass // Scheduling class
+	Level int     // Priority level within Realtime and BestEffort classes, from 0 (highest) to 7 (lowest)
+}
@@ -0,0 +1,32 @@
+// +build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// setIOPriority sets I/O priority of process <p> using ioprio_set, same as ionice does
+func setIOPriority(p *os.Process, prio IOPriority) error {
+	if prio.Class == IOClassNone {
+		return nil
+	}
+	if prio.Level < 0 || prio.Level > 7 {
+		return fmt.Errorf("invalid I/O priority level: %v", prio.Level)
+	}
+
+	// Kernel classes: 1 = realtime, 2 = best-effort, 3 = idle
+	value := int(prio.Class)<<ioprioClassShift | prio.Level
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(p.Pid), uintptr(value))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+// +build !linux,!windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+)
+
+// setIOPriority returns error as I/O priority of another process can not be set on this platform
+func setIOPriority(p *os.Process, prio IOPriority) error {
+	if prio.Class == IOClassNone {
+		return nil
+	}
+	return errors.New("I/O priority is not supported on this platform")
+}
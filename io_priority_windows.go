@@ -0,0 +1,59 @@
+// +build windows
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	processIoPriority = 33 // PROCESS_INFORMATION_CLASS ProcessIoPriority
+
+	ioPriorityVeryLow = 0 // Background mode
+	ioPriorityLow     = 1
+	ioPriorityNormal  = 2
+)
+
+var procNtSetInformationProcess = windows.NewLazySystemDLL("ntdll.dll").NewProc("NtSetInformationProcess")
+
+// setIOPriority sets I/O priority of process <p>.
+//
+// Idle class maps to very low (background mode) priority, best-effort levels 4-7 map to low priority,
+// other values map to normal priority
+func setIOPriority(p *os.Process, prio IOPriority) error {
+	if prio.Class == IOClassNone {
+		return nil
+	}
+	if prio.Level < 0 || prio.Level > 7 {
+		return fmt.Errorf("invalid I/O priority level: %v", prio.Level)
+	}
+
+	var value uint32 = ioPriorityNormal
+	switch {
+	case prio.Class == IOClassIdle:
+		value = ioPriorityVeryLow
+	case prio.Class == IOClassBestEffort && prio.Level >= 4:
+		value = ioPriorityLow
+	}
+
+	handle, err := windows.OpenProcess(windows.PROCESS_SET_INFORMATION, false, uint32(p.Pid))
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+
+	status, _, _ := procNtSetInformationProcess.Call(
+		uintptr(handle),
+		processIoPriority,
+		uintptr(unsafe.Pointer(&value)),
+		unsafe.Sizeof(value),
+	)
+	if status != 0 {
+		return windows.NTStatus(status)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultLaunchdMonitorInterval is an interval of polling job state if MonitorLaunchdJob got non-positive one
+const defaultLaunchdMonitorInterval = time.Second
+
+// launchdLabelRe matches labels safe to use as plist file name and launchctl argument, like reverse domain names
+var launchdLabelRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// LaunchdJob represents a launchd job on macOS
+type LaunchdJob struct {
+	Label             string   // Unique job label of letters, digits, dots, underscores and hyphens, e.g. "com.example.agent"
+	Command           string   // Command to run
+	Args              []string // Command arguments
+	Dir               string   // Working directory
+	KeepAlive         bool     // Restart the process whenever it exits?
+	RunAtLoad         bool     // Start the process as soon as the job is loaded?
+	StandardOutPath   string   // File to write StdOut of process to
+	StandardErrorPath string   // File to write StdErr of process to
+}
+
+// LaunchdStatus represents state of a loaded launchd job
+type LaunchdStatus struct {
+	Running        bool // Process is running?
+	PID            int  // PID of process, if running
+	LastExitStatus int  // Exit status of previous run
+}
+
+// Plist returns launchd property list describing the job
+func (j LaunchdJob) Plist() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	buf.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+
+	writeKey := func(key string) {
+		buf.WriteString("\t<key>")
+		xml.EscapeText(&buf, []byte(key))
+		buf.WriteString("</key>\n")
+	}
+	writeString := func(indent string, value string) {
+		buf.WriteString(indent + "<string>")
+		xml.EscapeText(&buf, []byte(value))
+		buf.WriteString("</string>\n")
+	}
+	writeBool := func(key string, value bool) {
+		writeKey(key)
+		buf.WriteString("\t<" + strconv.FormatBool(value) + "/>\n")
+	}
+
+	writeKey("Label")
+	writeString("\t", j.Label)
+	writeKey("ProgramArguments")
+	buf.WriteString("\t<array>\n")
+	for _, arg := range append([]string{j.Command}, j.Args...) {
+		writeString("\t\t", arg)
+	}
+	buf.WriteString("\t</array>\n")
+	if j.Dir != "" {
+		writeKey("WorkingDirectory")
+		writeString("\t", j.Dir)
+	}
+	writeBool("KeepAlive", j.KeepAlive)
+	writeBool("RunAtLoad", j.RunAtLoad)
+	if j.StandardOutPath != "" {
+		writeKey("StandardOutPath")
+		writeString("\t", j.StandardOutPath)
+	}
+	if j.StandardErrorPath != "" {
+		writeKey("StandardErrorPath")
+		writeString("\t", j.StandardErrorPath)
+	}
+
+	buf.WriteString("</dict>\n</plist>\n")
+	return buf.Bytes()
+}
+
+// checkLaunchdLabel returns error if <label> is empty or has characters other than letters, digits, dots,
+// underscores and hyphens, so it can't lead outside of LaunchAgents directory or be taken for launchctl option
+func checkLaunchdLabel(label string) error {
+	if !launchdLabelRe.MatchString(label) || label == "." || label == ".." {
+		return fmt.Errorf("invalid launchd job label: %q", label)
+	}
+	return nil
+}
+
+// parseLaunchctlList parses output of "launchctl list <label>"
+func parseLaunchctlList(out string) LaunchdStatus {
+	var status LaunchdStatus
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(parts[0]), `"`)
+		value, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(parts[1]), ";"))
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "PID":
+			status.PID = value
+			status.Running = true
+		case "LastExitStatus":
+			status.LastExitStatus = value
+		}
+	}
+	return status
+}
@@ -0,0 +1,97 @@
+// +build darwin
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SubmitLaunchdJob writes plist of <job> into ~/Library/LaunchAgents and loads it. Returns path to plist
+func SubmitLaunchdJob(job LaunchdJob) (string, error) {
+	path, err := launchAgentPath(job.Label)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, job.Plist(), 0644); err != nil {
+		return "", err
+	}
+
+	if out, err := exec.Command("launchctl", "load", "-w", path).CombinedOutput(); err != nil {
+		return path, fmt.Errorf("%v: %v", err, strings.TrimSpace(string(out)))
+	}
+	return path, nil
+}
+
+// RemoveLaunchdJob unloads job with <label> and removes its plist
+func RemoveLaunchdJob(label string) error {
+	path, err := launchAgentPath(label)
+	if err != nil {
+		return err
+	}
+	if out, err := exec.Command("launchctl", "unload", "-w", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %v", err, strings.TrimSpace(string(out)))
+	}
+	return os.Remove(path)
+}
+
+// LaunchdJobStatus returns state of loaded job with <label>
+func LaunchdJobStatus(label string) (LaunchdStatus, error) {
+	if err := checkLaunchdLabel(label); err != nil {
+		return LaunchdStatus{}, err
+	}
+	out, err := exec.Command("launchctl", "list", label).CombinedOutput()
+	if err != nil {
+		return LaunchdStatus{}, fmt.Errorf("%v: %v", err, strings.TrimSpace(string(out)))
+	}
+	return parseLaunchctlList(string(out)), nil
+}
+
+// MonitorLaunchdJob polls state of job with <label> every <interval> (1 second if not positive) and calls <onChange>
+// when it changes, until <ctx> is done or the job can not be queried anymore
+func MonitorLaunchdJob(ctx context.Context, label string, interval time.Duration, onChange func(LaunchdStatus)) error {
+	if interval <= 0 {
+		interval = defaultLaunchdMonitorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last LaunchdStatus
+	first := true
+	for {
+		status, err := LaunchdJobStatus(label)
+		if err != nil {
+			return err
+		}
+		if first || status != last {
+			onChange(status)
+			last, first = status, false
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// launchAgentPath returns path to plist of job with <label> in user LaunchAgents directory
+func launchAgentPath(label string) (string, error) {
+	if err := checkLaunchdLabel(label); err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", label+".plist"), nil
+}
@@ -0,0 +1,33 @@
+// +build !darwin
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errNoLaunchd is returned by launchd helpers on platforms other than macOS
+var errNoLaunchd = errors.New("launchd is only available on macOS")
+
+// SubmitLaunchdJob writes plist of <job> into ~/Library/LaunchAgents and loads it. Returns path to plist
+func SubmitLaunchdJob(job LaunchdJob) (string, error) {
+	return "", errNoLaunchd
+}
+
+// RemoveLaunchdJob unloads job with <label> and removes its plist
+func RemoveLaunchdJob(label string) error {
+	return errNoLaunchd
+}
+
+// LaunchdJobStatus returns state of loaded job with <label>
+func LaunchdJobStatus(label string) (LaunchdStatus, error) {
+	return LaunchdStatus{}, errNoLaunchd
+}
+
+// MonitorLaunchdJob polls state of job with <label> every <interval> (1 second if not positive) and calls <onChange>
+// when it changes, until <ctx> is done or the job can not be queried anymore
+func MonitorLaunchdJob(ctx context.Context, label string, interval time.Duration, onChange func(LaunchdStatus)) error {
+	return errNoLaunchd
+}
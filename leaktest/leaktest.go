@@ -0,0 +1,53 @@
+// Package leaktest provides test helpers checking executor does not leak goroutines, pipes and processes across executions
+package leaktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SCP002/executor"
+)
+
+// defaultTimeout is the time to let background goroutines and pipes of finished processes close
+const defaultTimeout = 5 * time.Second
+
+// Check remembers resources currently held by executor and returns function to defer,
+// failing <t> if more of them are held once it is called:
+//
+//	defer leaktest.Check(t)()
+func Check(t testing.TB) func() {
+	return CheckTimeout(t, defaultTimeout)
+}
+
+// CheckTimeout is like Check, but waits up to <timeout> for resources to be released
+func CheckTimeout(t testing.TB, timeout time.Duration) func() {
+	before := executor.GetDebugStats()
+	return func() {
+		t.Helper()
+		deadline := time.Now().Add(timeout)
+		for {
+			after := executor.GetDebugStats()
+			if after.Goroutines <= before.Goroutines && after.OpenPipes <= before.OpenPipes {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("executor leaked %v goroutines and %v pipes",
+					after.Goroutines-before.Goroutines, after.OpenPipes-before.OpenPipes)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// StartT starts a process with <opts>, killing it with its descendants once <t> and its subtests complete,
+// unless it was waited for
+func StartT(t testing.TB, opts executor.Options) executor.Result {
+	t.Helper()
+	opts.Cleanup = true
+	res := executor.Start(opts)
+	if res.StartOk && !opts.Wait {
+		t.Cleanup(func() { executor.Cleanup(res.PID) })
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadOptions represents options of running concurrent instances of a process for stress testing
+type LoadOptions struct {
+	Concurrency   int           // Number of instances running at the same time (1 if not set)
+	Duration      time.Duration // Time to keep launching new instances for, running ones are waited for after that
+	StatsInterval time.Duration // Interval between resource usage samples of each instance (1 second if not set)
+}
+
+// LoadResult represents outcome of a load test
+type LoadResult struct {
+	Runs           int           // Number of finished runs
+	Failures       int           // Number of runs which did not start or did not exit successfully
+	FailureRate    float64       // Failures divided by Runs
+	ExitCodes      map[int]int   // Number of runs by exit code (-1 if not started)
+	Min            time.Duration // Shortest run
+	Median         time.Duration // Median run
+	P95            time.Duration // 95th percentile run
+	P99            time.Duration // 99th percentile run
+	Max            time.Duration // Longest run
+	PeakRSS        uint64        // Highest sampled resident memory size of a single instance tree in bytes
+	PeakCPUPercent float64       // Highest sampled CPU usage of a single instance tree, 100 per fully used core
+	Elapsed        time.Duration // Time from start of the first run to end of the last run
+}
+
+// Load runs LoadOptions.Concurrency instances of a process described by <opts> in parallel, starting a new one as soon
+// as previous one finished, for LoadOptions.Duration. Process is always waited for and not printed unless
+// Options.Print is set. Cancellation of Options.Context stops launching new instances and kills running ones
+func Load(opts Options, lopts LoadOptions) (LoadResult, error) {
+	if lopts.Duration <= 0 {
+		return LoadResult{}, errors.New("load test duration is not set")
+	}
+	concurrency := lopts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	opts.Wait = true
+	opts.StatsInterval = lopts.StatsInterval
+
+	lr := LoadResult{ExitCodes: map[int]int{}}
+	var mu sync.Mutex
+	var times []time.Duration
+	onStats := opts.OnStats
+	opts.OnStats = func(s Stats) {
+		mu.Lock()
+		if s.RSS > lr.PeakRSS {
+			lr.PeakRSS = s.RSS
+		}
+		if s.CPUPercent > lr.PeakCPUPercent {
+			lr.PeakCPUPercent = s.CPUPercent
+		}
+		mu.Unlock()
+		if onStats != nil {
+			onStats(s)
+		}
+	}
+
+	start := time.Now()
+	deadline := start.Add(lopts.Duration)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if opts.Context != nil && opts.Context.Err() != nil {
+					return
+				}
+				runStart := time.Now()
+				res := Start(opts)
+				elapsed := time.Since(runStart)
+
+				mu.Lock()
+				lr.Runs++
+				lr.ExitCodes[res.ExitCode]++
+				if !res.DoneOk {
+					lr.Failures++
+				}
+				if res.StartOk {
+					times = append(times, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	lr.Elapsed = time.Since(start)
+
+	if lr.Runs > 0 {
+		lr.FailureRate = float64(lr.Failures) / float64(lr.Runs)
+	}
+	if len(times) > 0 {
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		lr.Min = times[0]
+		lr.Max = times[len(times)-1]
+		lr.Median = percentile(times, 50)
+		lr.P95 = percentile(times, 95)
+		lr.P99 = percentile(times, 99)
+	}
+	return lr, nil
+}
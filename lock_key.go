@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"sync"
+)
+
+// keyLock is mutex of Options.LockKey, shared by processes holding or waiting for it
+type keyLock struct {
+	mu   sync.Mutex
+	refs int // Processes holding or waiting for the lock
+}
+
+// Locks of keys held or waited for, removed once no process needs them
+var (
+	keyLocksMu sync.Mutex
+	keyLocks   = map[string]*keyLock{}
+)
+
+// lockKey blocks until no other process holds <key>, then takes it.
+// Returns function to call to release the key
+func lockKey(key string) func() {
+	keyLocksMu.Lock()
+	l, ok := keyLocks[key]
+	if !ok {
+		l = &keyLock{}
+		keyLocks[key] = l
+	}
+	l.refs++
+	keyLocksMu.Unlock()
+
+	l.mu.Lock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Unlock()
+			keyLocksMu.Lock()
+			defer keyLocksMu.Unlock()
+			l.refs--
+			if l.refs == 0 {
+				delete(keyLocks, key)
+			}
+		})
+	}
+}
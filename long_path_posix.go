@@ -0,0 +1,13 @@
+// +build !windows
+
+package executor
+
+// normalizeCommandPath returns <command> as is, long paths need no special handling on this platform
+func normalizeCommandPath(command string) string {
+	return command
+}
+
+// normalizeDir returns <dir> as is, long paths need no special handling on this platform
+func normalizeDir(dir string) string {
+	return dir
+}
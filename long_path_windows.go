@@ -0,0 +1,75 @@
+// +build windows
+
+package executor
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	longPathPrefix = `\\?\`
+	longUNCPrefix  = `\\?\UNC\`
+	// maxDirPath is a maximum length of current directory accepted by CreateProcess (MAX_PATH minus room for file name)
+	maxDirPath = 248
+	// maxPath is a maximum length of path usable without long path prefix (MAX_PATH)
+	maxPath = 260
+)
+
+// toLongPath returns absolute <path> with long path prefix, converting UNC shares to \\?\UNC\ form
+func toLongPath(path string) string {
+	if strings.HasPrefix(path, longPathPrefix) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if strings.HasPrefix(path, `\\`) {
+		return longUNCPrefix + path[2:]
+	}
+	return longPathPrefix + path
+}
+
+// fromLongPath returns <path> without long path prefix, converting \\?\UNC\ back to UNC share form
+func fromLongPath(path string) string {
+	if strings.HasPrefix(path, longUNCPrefix) {
+		return `\\` + path[len(longUNCPrefix):]
+	}
+	return strings.TrimPrefix(path, longPathPrefix)
+}
+
+// normalizeCommandPath returns <command> with long path prefix if it is a path too long for CreateProcess
+func normalizeCommandPath(command string) string {
+	if !strings.ContainsAny(command, `\/`) {
+		return command
+	}
+	if len(fromLongPath(command)) < maxPath {
+		return fromLongPath(command)
+	}
+	return toLongPath(command)
+}
+
+// normalizeDir returns working directory <dir> in form accepted by CreateProcess:
+// without long path prefix and, if too long, converted to short (8.3) form
+func normalizeDir(dir string) string {
+	if dir == "" {
+		return dir
+	}
+	plain := fromLongPath(dir)
+	if len(plain) < maxDirPath {
+		return plain
+	}
+
+	long, err := windows.UTF16FromString(toLongPath(plain))
+	if err != nil {
+		return plain
+	}
+	short := make([]uint16, len(long))
+	n, err := windows.GetShortPathName(&long[0], &short[0], uint32(len(short)))
+	if err != nil || int(n) > len(short) {
+		return plain
+	}
+	return fromLongPath(windows.UTF16ToString(short[:n]))
+}
@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// memoryCheckInterval is an interval between process memory usage samples
+const memoryCheckInterval = 250 * time.Millisecond
+
+// memoryWatchdog kills process when its resident memory size exceeds the limit
+type memoryWatchdog struct {
+	exceeded int32
+	done     chan struct{}
+}
+
+// startMemoryWatchdog starts sampling resident memory size of process <p>.
+// Sampling stops when the watchdog is stopped, the process exits (even if nobody waits for it), <canceled> is closed
+// or the process memory can not be queried anymore
+func startMemoryWatchdog(p *os.Process, limit uint64, canceled <-chan struct{}) *memoryWatchdog {
+	w := &memoryWatchdog{done: make(chan struct{})}
+	exited := processExited(p)
+
+	goTracked(func() {
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-exited:
+				return
+			case <-canceled:
+				return
+			case <-ticker.C:
+				rss, err := processRSS(p.Pid)
+				if err != nil {
+					return
+				}
+				if rss > limit {
+					atomic.StoreInt32(&w.exceeded, 1)
+					killWithDescendants(p)
+					return
+				}
+			}
+		}
+	})
+
+	return w
+}
+
+// stop stops sampling. Safe to call on nil watchdog
+func (w *memoryWatchdog) stop() {
+	if w != nil {
+		close(w.done)
+	}
+}
+
+// limitExceeded returns true if the process was killed by the watchdog. Safe to call on nil watchdog
+func (w *memoryWatchdog) limitExceeded() bool {
+	return w != nil && atomic.LoadInt32(&w.exceeded) == 1
+}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MustRun is like Run but panics if process did not finish successfully
+func MustRun(ctx context.Context, command string, args ...string) string {
+	out, err := Run(ctx, command, args...)
+	if err != nil {
+		panic(mustMessage(command, args, err))
+	}
+	return out
+}
+
+// MustOutput is like Output but panics if process did not finish successfully
+func MustOutput(opts Options) []byte {
+	out, err := Output(opts)
+	if err != nil {
+		panic(mustMessage(opts.Command, opts.Args, err))
+	}
+	return out
+}
+
+// mustMessage returns panic message describing failure <err> of <command> with <args>
+func mustMessage(command string, args []string, err error) string {
+	var sb strings.Builder
+	quoted := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{command}, args...) {
+		quoted = append(quoted, QuotePosix(arg))
+	}
+	fmt.Fprintf(&sb, "executor: %v failed\n", strings.Join(quoted, " "))
+	if exitErr, ok := err.(*ExitError); ok {
+		fmt.Fprintf(&sb, "exit code: %v (%v)\n", exitErr.ExitCode, exitErr.EndReason)
+		if stderr := strings.TrimSpace(exitErr.Stderr); stderr != "" {
+			fmt.Fprintf(&sb, "stderr:\n%v\n", stderr)
+		}
+	} else {
+		fmt.Fprintf(&sb, "error: %v\n", err)
+	}
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Stream identifiers used in multiplexed output, same as in Docker attach protocol
+const (
+	StreamStdin  byte = 0
+	StreamStdout byte = 1
+	StreamStderr byte = 2
+)
+
+// muxHeaderSize is a size of frame header: stream ID, 3 zero bytes and big-endian uint32 payload length
+const muxHeaderSize = 8
+
+// muxWriter writes data of several streams as frames into the single underlying writer
+type muxWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error // First write error, after which frames are dropped
+}
+
+// writeFrame writes <p> as a frame of <stream>
+func (m *muxWriter) writeFrame(stream byte, p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	var header [muxHeaderSize]byte
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(p)))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if _, m.err = m.w.Write(header[:]); m.err == nil {
+		_, m.err = m.w.Write(p)
+	}
+	return m.err
+}
+
+// streamWriter returns writer framing everything written into it as <stream>
+func (m *muxWriter) streamWriter(stream byte) io.Writer {
+	return muxStreamWriter{m: m, stream: stream}
+}
+
+// muxStreamWriter writes frames of a single stream
+type muxStreamWriter struct {
+	m      *muxWriter
+	stream byte
+}
+
+// Write implements io.Writer. Write errors are reported once to StdErr and never returned,
+// so a broken multiplexed output doesn't stop reading of the process output
+func (s muxStreamWriter) Write(p []byte) (int, error) {
+	s.m.mu.Lock()
+	failed := s.m.err != nil
+	s.m.mu.Unlock()
+	if failed {
+		return len(p), nil
+	}
+	if err := s.m.writeFrame(s.stream, p); err != nil {
+		diagln(err)
+	}
+	return len(p), nil
+}
+
+// DemuxOutput reads multiplexed output written with Options.MuxOutput from <r>
+// and writes payloads of StdOut and StdErr frames into <stdout> and <stderr> until EOF
+func DemuxOutput(r io.Reader, stdout io.Writer, stderr io.Writer) error {
+	var header [muxHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var dst io.Writer
+		switch header[0] {
+		case StreamStdout:
+			dst = stdout
+		case StreamStderr:
+			dst = stderr
+		case StreamStdin:
+			dst = io.Discard
+		default:
+			return fmt.Errorf("unknown stream ID: %v", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(dst, r, size); err != nil {
+			return err
+		}
+	}
+}
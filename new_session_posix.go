@@ -0,0 +1,16 @@
+// +build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setNewSession makes <cmd> start in a new session, so it is not affected by signals to the current one
+func setNewSession(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+}
@@ -0,0 +1,18 @@
+// +build windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// setNewSession makes <cmd> start in a new process group, so it does not receive Ctrl+C of the current one
+func setNewSession(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_NEW_PROCESS_GROUP
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolateNetwork returns error as network namespaces are not available on this platform.
+// Process is started with network access anyway
+func isolateNetwork(cmd *exec.Cmd) error {
+	return errors.New("network isolation is not supported on this platform, starting with network access")
+}
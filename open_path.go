@@ -0,0 +1,19 @@
+package executor
+
+import (
+	"context"
+)
+
+// OpenPath opens file, directory or URL <target> with the default application, like double clicking it does.
+// Returns Result.Err if the opener failed, like ExitError with StdErr tail
+func OpenPath(ctx context.Context, target string) (Result, error) {
+	command, args := openCommand(target)
+	res := Start(Options{
+		Command:         command,
+		Args:            args,
+		Wait:            true,
+		SeparateCapture: true,
+		Context:         ctx,
+	})
+	return res, res.Err
+}
@@ -0,0 +1,8 @@
+// +build darwin
+
+package executor
+
+// openCommand returns command opening <target> with the default application
+func openCommand(target string) (string, []string) {
+	return "open", []string{target}
+}
@@ -0,0 +1,8 @@
+// +build !windows,!darwin
+
+package executor
+
+// openCommand returns command opening <target> with the default application
+func openCommand(target string) (string, []string) {
+	return "xdg-open", []string{target}
+}
@@ -0,0 +1,22 @@
+// +build windows
+
+package executor
+
+import (
+	"strings"
+)
+
+// cmdMetaEscaper escapes characters cmd.exe interprets in unquoted arguments, which are common in URLs
+var cmdMetaEscaper = strings.NewReplacer("^", "^^", "&", "^&", "|", "^|", "<", "^<", ">", "^>", "(", "^(", ")", "^)", "%", "^%")
+
+// openCommand returns command opening <target> with the default application
+func openCommand(target string) (string, []string) {
+	// Quotes can not be escaped for cmd.exe, URL-encode them
+	target = strings.ReplaceAll(target, `"`, "%22")
+	// Arguments with spaces are quoted on command line and metacharacters are literal inside quotes
+	if !strings.ContainsAny(target, " \t") {
+		target = cmdMetaEscaper.Replace(target)
+	}
+	// Empty argument is the window title, otherwise quoted target would be taken as title
+	return "cmd", []string{"/d", "/c", "start", "", target}
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"fmt"
+)
+
+// Outcome represents overall status of execution
+type Outcome int
+
+const (
+	OutcomeStartFailed Outcome = iota // Process did not start
+	OutcomeRunning                    // Process started and was not waited for
+	OutcomeSucceeded                  // Process exited successfully
+	OutcomeFailed                     // Process exited with non-zero code
+	OutcomeTimedOut                   // Process was killed after Options.Timeout or deadline of Options.Context
+	OutcomeCanceled                   // Process was killed on cancellation of Options.Context
+	OutcomeKilled                     // Process was terminated by a signal or killed for exceeding a limit
+)
+
+// String returns name of the outcome
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeRunning:
+		return "running"
+	case OutcomeSucceeded:
+		return "succeeded"
+	case OutcomeFailed:
+		return "failed"
+	case OutcomeTimedOut:
+		return "timed out"
+	case OutcomeCanceled:
+		return "canceled"
+	case OutcomeKilled:
+		return "killed"
+	default:
+		return "start failed"
+	}
+}
+
+// outcome returns outcome of execution with <res>
+func outcome(res Result) Outcome {
+	switch {
+	case !res.StartOk:
+		return OutcomeStartFailed
+	case res.DoneOk:
+		return OutcomeSucceeded
+	case res.FailedMatch != "":
+		// Process may be killed because of the match
+		return OutcomeFailed
+	}
+	switch res.EndReason {
+	case EndNone:
+		return OutcomeRunning
+	case EndExited:
+		return OutcomeFailed
+	case EndTimeout:
+		return OutcomeTimedOut
+	case EndCanceled:
+		return OutcomeCanceled
+	default:
+		return OutcomeKilled
+	}
+}
+
+// setOutcome sets Outcome and Err of <res> of process of <command>, keeping error of start failure if it is set
+func (res *Result) setOutcome(command string) {
+	res.Outcome = outcome(*res)
+	switch res.Outcome {
+	case OutcomeStartFailed:
+		if res.Err == nil {
+			res.Err = fmt.Errorf("%w: %v", ErrNotStarted, command)
+		}
+	case OutcomeRunning:
+		res.Err = nil
+	case OutcomeFailed:
+		if res.FailedMatch != "" {
+			res.Err = fmt.Errorf("%w: %q", ErrOutputMatched, res.FailedMatch)
+		} else if res.MissingMatch && res.ExitCode == 0 {
+			res.Err = fmt.Errorf("%w: %v", ErrOutputMissing, command)
+		} else {
+			res.Err = newExitError(command, *res)
+		}
+	default:
+		res.Err = newExitError(command, *res)
+	}
+}
+
+// startFailed reports <err> preventing start of process and sets it as error of <res>, returning <res>
+func startFailed(res Result, err error) Result {
+	diagln(err)
+	res.Err = err
+	return res
+}
@@ -0,0 +1,427 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// outputScanner reads StdOut and StdErr of process to print, capture and pass them to callbacks.
+// Both streams are always read to the end, even if nothing consumes the output
+type outputScanner struct {
+	opts        Options
+	cmd         *exec.Cmd
+	stdout      io.Reader
+	stderr      io.Reader
+	mu          sync.Mutex // Serializes handling of characters from both streams
+	wg          sync.WaitGroup
+	outSb       spillBuffer
+	stdoutSb    spillBuffer
+	stderrSb    spillBuffer
+	stdoutBytes int64
+	stderrBytes int64
+	mux         *muxWriter
+	answerer    *autoAnswerer
+	stderrTail  *tailBuffer
+	decoders    [2]decodeFunc // Decoders of StdOut and StdErr
+	decodeErr   *DecodeError
+	printer     *printer
+	finishOnce  sync.Once
+	done        chan struct{} // Closed once both streams are closed
+}
+
+// newOutputScanner returns scanner of <stdout> and <stderr> of <cmd> configured by <opts>
+func newOutputScanner(opts Options, cmd *exec.Cmd, stdout io.Reader, stderr io.Reader) *outputScanner {
+	s := &outputScanner{opts: opts, cmd: cmd, stdout: stdout, stderr: stderr, done: make(chan struct{})}
+	s.outSb.limit = opts.CaptureMemoryLimit
+	s.stdoutSb.limit = opts.CaptureMemoryLimit
+	s.stderrSb.limit = opts.CaptureMemoryLimit
+	if opts.Print {
+		s.printer = newPrinter(opts.PrintBuffering, opts.PrintFlushInterval, &s.mu)
+		s.printer.tintStderr = opts.TintStderr
+		s.printer.stderrPrefix = opts.StderrPrefix
+		if opts.TintStderr {
+			enableVirtualTerminal()
+		}
+		s.printer.group = opts.groupPrinter
+		s.printer.groupPrefix = opts.groupPrefix
+	}
+	if opts.MuxOutput != nil {
+		s.mux = &muxWriter{w: opts.MuxOutput}
+	}
+	tailSize := opts.StderrTailSize
+	if tailSize == 0 {
+		tailSize = defaultStderrTailSize
+	}
+	if tailSize > 0 {
+		s.stderrTail = newTailBuffer(tailSize)
+	}
+	for _, stream := range []byte{StreamStdout, StreamStderr} {
+		cp := streamCodePage(opts, stream)
+		if cp == CodePageUTF8 || len(opts.Transforms) > 0 {
+			continue
+		}
+		decode, err := newDecoder(cp)
+		if err != nil {
+			diagln(err)
+		}
+		s.decoders[stream-StreamStdout] = decode
+	}
+	return s
+}
+
+// outputScanned returns true if output must be read by the scanner for <opts>, rather than connected directly.
+// Printed output must be scanned only if it is transformed or marked
+func outputScanned(opts Options) bool {
+	if opts.Capture || opts.SeparateCapture || opts.OnChar != nil || opts.OnLine != nil || opts.MuxOutput != nil ||
+		len(opts.AutoAnswer) > 0 || opts.ReadRateLimit > 0 {
+		return true
+	}
+	decoded := streamCodePage(opts, StreamStdout) != CodePageUTF8 || streamCodePage(opts, StreamStderr) != CodePageUTF8
+	return opts.Print && (len(opts.Transforms) > 0 || decoded || opts.TintStderr || opts.StderrPrefix != "" ||
+		opts.groupPrinter != nil)
+}
+
+// streamCodePage returns code page of <stream> set in <opts>
+func streamCodePage(opts Options, stream byte) CodePage {
+	cp := opts.StdoutEncoding
+	if stream == StreamStderr {
+		cp = opts.StderrEncoding
+	}
+	if cp == 0 {
+		cp = opts.Encoding
+	}
+	if cp == 0 {
+		cp = defaultCodePage()
+	}
+	return cp
+}
+
+// start starts scanning in background. Must be called after process started. Safe to call on nil scanner
+func (s *outputScanner) start() {
+	if s == nil {
+		return
+	}
+	if s.printer != nil {
+		s.printer.start()
+	}
+	// StdOut is not read if it is connected to the next process of Pipeline or not consumed
+	if s.stdout != nil {
+		s.wg.Add(1)
+		goTracked(func() { s.scan(s.stdout, StreamStdout, &s.stdoutBytes) })
+	}
+	if s.stderr != nil {
+		s.wg.Add(1)
+		goTracked(func() { s.scan(s.stderr, StreamStderr, &s.stderrBytes) })
+	}
+
+	goTracked(func() {
+		s.wg.Wait()
+		s.finish()
+	})
+}
+
+// finish releases resources once both streams are closed: no prompts can appear and no output needs to be printed
+func (s *outputScanner) finish() {
+	s.finishOnce.Do(func() {
+		// Read ends are not needed anymore, even if the process is never waited for
+		s.closeReaders()
+		if s.printer != nil {
+			s.printer.stop()
+		}
+		s.mu.Lock()
+		s.answerer.stop()
+		s.outSb.close()
+		s.stdoutSb.close()
+		s.stderrSb.close()
+		if s.printer != nil {
+			s.printer.close()
+			s.printer.flush()
+		}
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// finished returns channel closed once both streams are closed. Channel of nil scanner is closed
+func (s *outputScanner) finished() <-chan struct{} {
+	if s == nil {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return s.done
+}
+
+// wait waits until both streams are closed and printed output is flushed. Safe to call on nil scanner
+func (s *outputScanner) wait() {
+	if s != nil {
+		s.wg.Wait()
+		s.finish()
+	}
+}
+
+// waitFor waits until both streams are closed and printed output is flushed, for at most <timeout>.
+// Streams still open after that are closed, dropping their unread output.
+// Returns false if it happened. Safe to call on nil scanner
+func (s *outputScanner) waitFor(timeout time.Duration) bool {
+	if s == nil {
+		return true
+	}
+	done := make(chan struct{})
+	goTracked(func() {
+		s.wg.Wait()
+		close(done)
+	})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	drained := true
+	select {
+	case <-done:
+	case <-timer.C:
+		drained = false
+		// Pending reads fail once their pipes are closed
+		s.closeReaders()
+		<-done
+	}
+	s.finish()
+	return drained
+}
+
+// closeReaders closes StdOut and StdErr read ends, if they can be closed
+func (s *outputScanner) closeReaders() {
+	for _, r := range []io.Reader{s.stdout, s.stderr} {
+		if c, ok := r.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+// outputPipe returns pipe connected to <stream> of <cmd>.
+// If <own> is set, the pipe is created by executor instead of <cmd>, so Wait does not close its read end,
+// and its write end is returned to close once the process is started
+func outputPipe(cmd *exec.Cmd, stream byte, own bool) (*trackedPipe, *trackedPipe, error) {
+	if !own {
+		newPipe := cmd.StdoutPipe
+		if stream == StreamStderr {
+			newPipe = cmd.StderrPipe
+		}
+		r, err := newPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		return trackPipe(r), nil, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if stream == StreamStderr {
+		cmd.Stderr = w
+	} else {
+		cmd.Stdout = w
+	}
+	return trackPipe(r), trackPipe(w), nil
+}
+
+// fill sets captured output and byte counters of <res>. Safe to call on nil scanner
+func (s *outputScanner) fill(res *Result) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res.Output = s.outSb.mem.String()
+	res.OutputFile = s.outSb.path()
+	res.Stdout = s.stdoutSb.mem.String()
+	res.StdoutFile = s.stdoutSb.path()
+	res.Stderr = s.stderrSb.mem.String()
+	res.StderrFile = s.stderrSb.path()
+	res.StdoutBytes = atomic.LoadInt64(&s.stdoutBytes)
+	res.StderrBytes = atomic.LoadInt64(&s.stderrBytes)
+	if s.stderrTail != nil {
+		res.StderrTail = s.stderrTail.String()
+	}
+	if s.decodeErr != nil {
+		res.DecodeError = s.decodeErr
+	}
+}
+
+// scanBufferPool holds buffers of output scanners, to not allocate them for each started process
+var scanBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 4096)
+		return &buf
+	},
+}
+
+// newline is LF line ending
+var newline = []byte{'\n'}
+
+// isByte returns true if <p> consists of single byte <b>
+func isByte(p []byte, b byte) bool {
+	return len(p) == 1 && p[0] == b
+}
+
+// scan reads characters of <stream> from <r> until EOF, counting bytes read into <counter>
+func (s *outputScanner) scan(r io.Reader, stream byte, counter *int64) {
+	defer s.wg.Done()
+
+	if s.mux != nil {
+		r = io.TeeReader(r, s.mux.streamWriter(stream))
+	}
+	if s.opts.ReadRateLimit > 0 {
+		r = newThrottledReader(r, s.opts.ReadRateLimit)
+	}
+	r = &countingReader{r: r, n: counter}
+	if len(s.opts.Transforms) > 0 {
+		for _, transform := range s.opts.Transforms {
+			r = transform(r)
+		}
+	} else if decode := s.decoders[stream-StreamStdout]; decode != nil {
+		r = newDecodingReader(r, decode)
+	}
+	if stream == StreamStderr && s.stderrTail != nil {
+		r = io.TeeReader(r, s.stderrTail)
+	}
+
+	// Characters one by one are only needed for OnChar without coalescing and prompt matching
+	if (s.opts.OnChar == nil || s.opts.CoalesceInterval != 0) && s.answerer == nil {
+		s.scanChunks(r, stream)
+		_, _ = io.Copy(io.Discard, r)
+		return
+	}
+
+	scanner := bufio.NewScanner(r)
+	buf := scanBufferPool.Get().(*[]byte)
+	defer scanBufferPool.Put(buf)
+	scanner.Buffer(*buf, bufio.MaxScanTokenSize)
+	scanner.Split(runeSplitter(s.opts.InvalidBytes, func(offset int64) {
+		s.invalidByte(stream, offset)
+	}))
+	// Lines are built per stream so lines of StdOut and StdErr don't mix
+	var lineBuf bytes.Buffer
+	// Unterminated line to match prompts against
+	var prompt *promptLine
+	if s.answerer != nil {
+		prompt = newPromptLine(s.answerer, &s.mu)
+		defer prompt.stop()
+	}
+	// Previous character was CR, so the following LF is a part of the same line ending
+	pendingCR := false
+
+	// Characters are handled as bytes of scanner buffer, strings are only made for callbacks
+	for scanner.Scan() {
+		char := scanner.Bytes()
+		// Character to capture and build lines of, empty if it should be skipped
+		text := char
+		if s.opts.NormalizeNewlines {
+			switch {
+			case isByte(char, '\r'):
+				text = newline
+				pendingCR = true
+			case isByte(char, '\n') && pendingCR:
+				text = nil
+				pendingCR = false
+			default:
+				pendingCR = false
+			}
+		}
+		s.mu.Lock()
+		if s.opts.Print {
+			s.printer.writeStream(stream, char)
+		}
+		if s.opts.Capture {
+			s.outSb.Write(text)
+		}
+		if s.opts.SeparateCapture {
+			if stream == StreamStdout {
+				s.stdoutSb.Write(text)
+			} else {
+				s.stderrSb.Write(text)
+			}
+		}
+		// Char callback
+		if s.opts.OnChar != nil {
+			stopWatch := s.watchCallback("OnChar")
+			s.opts.OnChar(string(char), s.cmd.Process)
+			stopWatch()
+		}
+		// Answer prompts
+		if prompt != nil {
+			prompt.write(char)
+		}
+		// Build the line
+		if s.opts.OnLine != nil && len(text) > 0 {
+			if !isByte(text, '\n') && !isByte(text, '\r') {
+				lineBuf.Write(text)
+			} else {
+				// Line callback
+				stopWatch := s.watchCallback("OnLine")
+				s.opts.OnLine(lineBuf.String(), s.cmd.Process)
+				stopWatch()
+				lineBuf.Reset()
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	// Keep draining if scanner stopped on error, so the process never blocks on a full pipe
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// invalidByte records position of invalid byte at <offset> of <stream>, if it is the first one and
+// Options.InvalidBytes is InvalidError
+func (s *outputScanner) invalidByte(stream byte, offset int64) {
+	if s.opts.InvalidBytes != InvalidError {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.decodeErr == nil {
+		name := "stdout"
+		if stream == StreamStderr {
+			name = "stderr"
+		}
+		s.decodeErr = &DecodeError{Stream: name, Offset: offset}
+	}
+}
+
+// watchCallback starts timer reporting <callback> as slow once it runs longer than Options.SlowCallbackThreshold.
+// While callback is blocked, pipe is not read and the process blocks on writing output.
+// Returns function stopping the timer
+func (s *outputScanner) watchCallback(callback string) func() {
+	threshold := s.opts.SlowCallbackThreshold
+	if threshold <= 0 {
+		return func() {}
+	}
+
+	timer := time.AfterFunc(threshold, func() {
+		if s.opts.OnSlowCallback != nil {
+			s.opts.OnSlowCallback(callback, threshold)
+		} else {
+			diagf("%v callback is blocking output reading for more than %v\n", callback, threshold)
+		}
+	})
+	return func() { timer.Stop() }
+}
+
+// countingReader counts bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+// Read implements io.Reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"os"
+	"regexp"
+	"sync"
+)
+
+// failMatcher checks lines against Options.FailIfOutputMatches, remembering the first matching line
+type failMatcher struct {
+	mu       sync.Mutex
+	patterns []*regexp.Regexp
+	kill     bool
+	matched  string
+	found    bool
+}
+
+// newFailMatcher returns matcher of lines against <patterns>, killing the process on match if <kill> is set
+func newFailMatcher(patterns []*regexp.Regexp, kill bool) *failMatcher {
+	return &failMatcher{patterns: patterns, kill: kill}
+}
+
+// wrapOnLine returns line callback checking lines before passing them to <onLine> (optional)
+func (m *failMatcher) wrapOnLine(onLine func(l string, p *os.Process)) func(l string, p *os.Process) {
+	return func(l string, p *os.Process) {
+		m.check(l, p)
+		if onLine != nil {
+			onLine(l, p)
+		}
+	}
+}
+
+// check remembers line <l> of process <p> if it is the first one matching, killing the process if needed
+func (m *failMatcher) check(l string, p *os.Process) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.found {
+		return
+	}
+	for _, pattern := range m.patterns {
+		if pattern.MatchString(l) {
+			m.found = true
+			m.matched = l
+			if m.kill {
+				killWithDescendants(p)
+			}
+			return
+		}
+	}
+}
+
+// fill marks <res> as failed if a line matched. Safe to call on nil matcher
+func (m *failMatcher) fill(res *Result) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.found {
+		res.DoneOk = false
+		res.FailedMatch = m.matched
+	}
+}
+
+// requireMatcher checks lines against Options.RequireOutputMatch
+type requireMatcher struct {
+	mu      sync.Mutex
+	pattern *regexp.Regexp
+	found   bool
+}
+
+// wrapOnLine returns line callback checking lines before passing them to <onLine> (optional)
+func (m *requireMatcher) wrapOnLine(onLine func(l string, p *os.Process)) func(l string, p *os.Process) {
+	return func(l string, p *os.Process) {
+		m.mu.Lock()
+		if !m.found && m.pattern.MatchString(l) {
+			m.found = true
+		}
+		m.mu.Unlock()
+		if onLine != nil {
+			onLine(l, p)
+		}
+	}
+}
+
+// fill marks <res> of finished process as failed if no line matched. Safe to call on nil matcher
+func (m *requireMatcher) fill(res *Result) {
+	if m == nil || res.EndReason == EndNone {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.found {
+		res.DoneOk = false
+		res.MissingMatch = true
+	}
+}
@@ -0,0 +1,137 @@
+package executor
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Pipeline represents processes with StdOut of each connected to StdIn of the next one, like "a | b | c" in shells.
+//
+// Processes are connected with OS pipes directly, so data between them is not copied through the current process.
+// StdOut of the last process and StdErr of every process are handled as set by their options.
+// Options.Cache and Options.Backend are not supported, stages with them fail to start with ErrUnsupported
+type Pipeline struct {
+	Commands []Options // Options of processes, in order of data flow
+}
+
+// RunningPipeline represents started Pipeline, which can be waited for or stopped as a whole
+type RunningPipeline struct {
+	results []Result
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	procs   []*os.Process   // Started processes by stage, nil if not started yet
+	done    []chan struct{} // Closed once process of stage finished or failed to start
+	stopped bool            // Processes are killed as soon as they start
+}
+
+// Run starts all processes, waits for them to finish and returns their results in order
+func (p Pipeline) Run() []Result {
+	return p.Start().Wait()
+}
+
+// Start starts all processes and returns RunningPipeline to wait for or stop them
+func (p Pipeline) Start() *RunningPipeline {
+	rp := &RunningPipeline{
+		results: make([]Result, len(p.Commands)),
+		procs:   make([]*os.Process, len(p.Commands)),
+		done:    make([]chan struct{}, len(p.Commands)),
+	}
+	for i := range rp.done {
+		rp.done[i] = make(chan struct{})
+	}
+	if len(p.Commands) == 0 {
+		return rp
+	}
+
+	commands := make([]Options, len(p.Commands))
+	copy(commands, p.Commands)
+	for i := 0; i < len(commands)-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			diagln(err)
+			for j := 0; j < i; j++ {
+				commands[j].closePipeEnds()
+			}
+			for j := range rp.results {
+				rp.results[j] = Result{ExitCode: -1}
+				close(rp.done[j])
+			}
+			return rp
+		}
+		pipesOpened(2)
+		commands[i].pipeOut = w
+		commands[i+1].pipeIn = r
+	}
+
+	for i := range commands {
+		i := i
+		commands[i].Wait = true
+		commands[i].onStarted = func(proc *os.Process) { rp.started(i, proc) }
+		rp.wg.Add(1)
+		go func() {
+			defer rp.wg.Done()
+			defer close(rp.done[i])
+			rp.results[i] = Start(commands[i])
+		}()
+	}
+	return rp
+}
+
+// started remembers <proc> of stage <i>, killing it if pipeline is already stopped
+func (rp *RunningPipeline) started(i int, proc *os.Process) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.procs[i] = proc
+	if rp.stopped {
+		killWithDescendants(proc)
+	}
+}
+
+// Wait waits for all processes to finish and returns their results in order
+func (rp *RunningPipeline) Wait() []Result {
+	rp.wg.Wait()
+	return rp.results
+}
+
+// Kill kills all processes, from the last to the first one, so no stage is left writing into a dead consumer.
+// Processes not started yet are killed as soon as they start
+func (rp *RunningPipeline) Kill() {
+	rp.Stop(0)
+}
+
+// Stop asks all processes to exit, from the last to the first one, giving each <grace> time before it gets killed.
+// Processes are killed at once if <grace> is not positive or they can't be asked to exit (on Windows).
+// Processes not started yet are killed as soon as they start
+func (rp *RunningPipeline) Stop(grace time.Duration) {
+	rp.mu.Lock()
+	rp.stopped = true
+	procs := append([]*os.Process(nil), rp.procs...)
+	rp.mu.Unlock()
+
+	for i := len(procs) - 1; i >= 0; i-- {
+		proc := procs[i]
+		if proc == nil {
+			continue
+		}
+		if grace > 0 && terminate(proc) {
+			timer := time.NewTimer(grace)
+			select {
+			case <-rp.done[i]:
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+		}
+		killWithDescendants(proc)
+	}
+}
+
+// closePipeEnds closes pipe ends connecting process to its neighbours in Pipeline, if any. Safe to call more than once
+func (opts Options) closePipeEnds() {
+	for _, f := range []*os.File{opts.pipeIn, opts.pipeOut} {
+		if f != nil && f.Close() == nil {
+			pipesOpened(-1)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"sync"
+)
+
+// PolicyRequest represents a process about to be started
+type PolicyRequest struct {
+	Path string   // Resolved path of executable (as given, if it can't be resolved or is remote)
+	Args []string // Command arguments
+	Dir  string   // Working directory
+	User string   // User name of the parent process
+}
+
+// Policy decides whether a process is allowed to start.
+// Check should return error wrapping ErrPolicyDenied to reject execution
+type Policy interface {
+	Check(req PolicyRequest) error
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   Policy
+)
+
+// SetPolicy sets global policy consulted before starting any process. Nil allows everything
+func SetPolicy(p Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// checkPolicy consults the global policy, if set, about starting <command> with <args> in <dir>
+func checkPolicy(command string, args []string, dir string) error {
+	return consultPolicy(command, args, dir, true)
+}
+
+// CheckRemotePolicy consults the global policy, if set, about starting <command> with <args> in <dir> on another
+// machine or device. Unlike for local processes, <command> is passed to the policy as is, without resolving it.
+// For backends which run commands through a local client, like adb or ssh, the policy check of Start sees only
+// the client
+func CheckRemotePolicy(command string, args []string, dir string) error {
+	return consultPolicy(command, args, dir, false)
+}
+
+// consultPolicy consults the global policy, if set, about starting <command> with <args> in <dir>,
+// resolving path of <command> on the local machine if <resolve> is set
+func consultPolicy(command string, args []string, dir string, resolve bool) error {
+	policyMu.RLock()
+	p := policy
+	policyMu.RUnlock()
+	if p == nil {
+		return nil
+	}
+
+	req := PolicyRequest{Path: command, Args: args, Dir: dir}
+	if resolve {
+		if path, err := resolveCommand(command, dir); err == nil {
+			req.Path = path
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		req.User = u.Username
+	}
+	return p.Check(req)
+}
+
+// checkExecution consults the global policy and verifies executable of <opts> started in <dir>, like Start does,
+// for processes started outside of it
+func checkExecution(opts Options, dir string) error {
+	if err := checkPolicy(opts.Command, opts.Args, dir); err != nil {
+		return err
+	}
+	return verifyExecutable(opts, dir)
+}
+
+// ListPolicy allows or denies commands by glob patterns (see filepath.Match) matched against
+// both full path and base name of executable. Deny patterns take precedence
+type ListPolicy struct {
+	Allow []string // Patterns of allowed commands. Everything not denied is allowed if empty
+	Deny  []string // Patterns of denied commands
+}
+
+// Check implements Policy
+func (l ListPolicy) Check(req PolicyRequest) error {
+	if matchCommand(req.Path, l.Deny) {
+		return fmt.Errorf("%w: %v is denied", ErrPolicyDenied, req.Path)
+	}
+	if len(l.Allow) > 0 && !matchCommand(req.Path, l.Allow) {
+		return fmt.Errorf("%w: %v is not allowed", ErrPolicyDenied, req.Path)
+	}
+	return nil
+}
+
+// matchCommand returns true if <path> or its base name matches any of <patterns>
+func matchCommand(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
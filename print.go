@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ANSI escape sequences of StdErr tint
+const (
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// defaultPrintFlushInterval is the interval of flushing printed output if Options.PrintFlushInterval is not set
+const defaultPrintFlushInterval = 100 * time.Millisecond
+
+// PrintBuffering represents how printed output is buffered before writing to console
+type PrintBuffering int
+
+const (
+	PrintUnbuffered      PrintBuffering = iota // Write as soon as output is read
+	PrintLineBuffered                          // Write once a line is complete
+	PrintIntervalFlushed                       // Write every Options.PrintFlushInterval
+)
+
+// printer writes printed output of both streams to console, buffered as set by Options.PrintBuffering.
+// Methods except start and stop must be called with mutex of the scanner locked
+type printer struct {
+	mode     PrintBuffering
+	w        io.Writer
+	buf      *bufio.Writer
+	interval time.Duration
+	mu       *sync.Mutex
+	done     chan struct{}
+	exited   chan struct{}
+
+	tintStderr      bool   // Print StdErr in red?
+	stderrPrefix    string // Text to print before each line of StdErr
+	stderrLineStart bool   // Next StdErr byte starts a line?
+
+	group       *groupPrinter   // Printer shared by processes of Group, if any
+	groupPrefix string          // Prefix of lines in Group
+	partial     [2]bytes.Buffer // Unterminated lines of StdOut and StdErr in Group
+}
+
+// newPrinter returns printer in <mode>. In interval mode it flushes every <interval> with <mu> locked once started
+func newPrinter(mode PrintBuffering, interval time.Duration, mu *sync.Mutex) *printer {
+	p := &printer{mode: mode, w: os.Stdout, interval: interval, mu: mu, stderrLineStart: true}
+	if mode != PrintUnbuffered {
+		p.buf = bufio.NewWriterSize(os.Stdout, 64*1024)
+	}
+	return p
+}
+
+// start starts flushing in interval mode
+func (p *printer) start() {
+	if p.mode != PrintIntervalFlushed {
+		return
+	}
+	interval := p.interval
+	if interval <= 0 {
+		interval = defaultPrintFlushInterval
+	}
+	p.done = make(chan struct{})
+	p.exited = make(chan struct{})
+	goTracked(func() {
+		defer close(p.exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.flush()
+				p.mu.Unlock()
+			}
+		}
+	})
+}
+
+// writeStream prints <b> of <stream>, marking StdErr with tint and prefix
+func (p *printer) writeStream(stream byte, b []byte) {
+	if stream != StreamStderr || (!p.tintStderr && p.stderrPrefix == "") {
+		p.emit(stream, b)
+		return
+	}
+
+	marked := make([]byte, 0, len(b)+len(ansiRed)+len(ansiReset)+len(p.stderrPrefix))
+	if p.tintStderr {
+		marked = append(marked, ansiRed...)
+	}
+	for len(b) > 0 {
+		if p.stderrLineStart {
+			marked = append(marked, p.stderrPrefix...)
+			p.stderrLineStart = false
+		}
+		idx := bytes.IndexByte(b, '\n')
+		if idx < 0 {
+			marked = append(marked, b...)
+			break
+		}
+		marked = append(marked, b[:idx+1]...)
+		b = b[idx+1:]
+		p.stderrLineStart = true
+	}
+	if p.tintStderr {
+		marked = append(marked, ansiReset...)
+	}
+	p.emit(stream, marked)
+}
+
+// emit prints <b> of <stream>, whole lines at once with prefix if the process is a part of Group
+func (p *printer) emit(stream byte, b []byte) {
+	if p.group == nil {
+		p.write(b)
+		return
+	}
+	partial := &p.partial[stream-StreamStdout]
+	for {
+		idx := bytes.IndexByte(b, '\n')
+		if idx < 0 {
+			partial.Write(b)
+			return
+		}
+		partial.Write(b[:idx+1])
+		p.group.writeLine(p.groupPrefix, partial.Bytes())
+		partial.Reset()
+		b = b[idx+1:]
+	}
+}
+
+// close prints unterminated lines of Group process
+func (p *printer) close() {
+	if p.group == nil {
+		return
+	}
+	for i := range p.partial {
+		if p.partial[i].Len() > 0 {
+			p.partial[i].WriteByte('\n')
+			p.group.writeLine(p.groupPrefix, p.partial[i].Bytes())
+			p.partial[i].Reset()
+		}
+	}
+}
+
+// write prints <b>
+func (p *printer) write(b []byte) {
+	if p.buf == nil {
+		_, _ = p.w.Write(b)
+		return
+	}
+	_, _ = p.buf.Write(b)
+	if p.mode == PrintLineBuffered && (bytes.IndexByte(b, '\n') >= 0 || bytes.IndexByte(b, '\r') >= 0) {
+		p.flush()
+	}
+}
+
+// flush writes buffered output
+func (p *printer) flush() {
+	if p.buf != nil {
+		_ = p.buf.Flush()
+	}
+}
+
+// stop stops interval flushing. Must be called without mutex of the scanner locked
+func (p *printer) stop() {
+	if p.done != nil {
+		close(p.done)
+		<-p.exited
+		p.done = nil
+	}
+}
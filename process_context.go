@@ -0,0 +1,38 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// Contexts of processes with output being scanned, by process
+var (
+	processContextsMu sync.Mutex
+	processContexts   = map[*os.Process]context.Context{}
+)
+
+// ProcessContext returns Options.Context process <p> was started with, to get values like request ID in OnChar and
+// OnLine callbacks, to correlate output with the request which started the process.
+// Returns context.Background() if context was not set or output of the process is not read anymore
+func ProcessContext(p *os.Process) context.Context {
+	processContextsMu.Lock()
+	defer processContextsMu.Unlock()
+	if ctx, ok := processContexts[p]; ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// setProcessContext remembers <ctx> of <p> until <done> is closed
+func setProcessContext(p *os.Process, ctx context.Context, done <-chan struct{}) {
+	processContextsMu.Lock()
+	processContexts[p] = ctx
+	processContextsMu.Unlock()
+	goTracked(func() {
+		<-done
+		processContextsMu.Lock()
+		delete(processContexts, p)
+		processContextsMu.Unlock()
+	})
+}
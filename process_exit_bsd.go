@@ -0,0 +1,38 @@
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// processExited returns channel closed once process <p> exits. The process is not reaped, so it can still be waited
+// for
+func processExited(p *os.Process) <-chan struct{} {
+	exited := make(chan struct{})
+	goTracked(func() {
+		defer close(exited)
+		kq, err := syscall.Kqueue()
+		if err != nil {
+			diagln(err)
+			return
+		}
+		defer syscall.Close(kq)
+
+		var ev syscall.Kevent_t
+		syscall.SetKevent(&ev, p.Pid, syscall.EVFILT_PROC, syscall.EV_ADD|syscall.EV_ONESHOT)
+		ev.Fflags = syscall.NOTE_EXIT
+		// ESRCH means the process exited already, zombies included
+		if _, err := syscall.Kevent(kq, []syscall.Kevent_t{ev}, nil, nil); err != nil {
+			return
+		}
+		events := make([]syscall.Kevent_t, 1)
+		for {
+			if _, err := syscall.Kevent(kq, nil, events, nil); err != syscall.EINTR {
+				return
+			}
+		}
+	})
+	return exited
+}
@@ -0,0 +1,33 @@
+// +build linux
+
+package executor
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	waitPID    = 1         // P_PID of waitid
+	waitNoWait = 0x1000000 // WNOWAIT of waitid, leaving the process to be waited for
+)
+
+// processExited returns channel closed once process <p> exits. The process is not reaped, so it can still be waited
+// for
+func processExited(p *os.Process) <-chan struct{} {
+	exited := make(chan struct{})
+	goTracked(func() {
+		defer close(exited)
+		var info [128]byte // siginfo_t
+		for {
+			_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, waitPID, uintptr(p.Pid),
+				uintptr(unsafe.Pointer(&info[0])), syscall.WEXITED|waitNoWait, 0, 0)
+			// ECHILD means the process was waited for already
+			if errno != syscall.EINTR {
+				return
+			}
+		}
+	})
+	return exited
+}
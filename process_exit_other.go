@@ -0,0 +1,34 @@
+// +build !linux,!windows,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processExitCheckInterval is an interval between checks of process state
+const processExitCheckInterval = 250 * time.Millisecond
+
+// processExited returns channel closed once process <p> exits. The process is not reaped, so it can still be waited
+// for
+func processExited(p *os.Process) <-chan struct{} {
+	exited := make(chan struct{})
+	goTracked(func() {
+		defer close(exited)
+		ticker := time.NewTicker(processExitCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Exited process stays a zombie until it is waited for
+			out, err := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(p.Pid)).Output()
+			state := strings.TrimSpace(string(out))
+			if err != nil || state == "" || strings.HasPrefix(state, "Z") {
+				return
+			}
+		}
+	})
+	return exited
+}
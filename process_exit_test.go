@@ -0,0 +1,27 @@
+package executor
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestProcessExitedLeavesProcessToWait(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell is not available on Windows")
+	}
+	cmd := exec.Command("/bin/sh", "-c", "sleep 0.2; exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-processExited(cmd.Process):
+	case <-time.After(5 * time.Second):
+		t.Fatal("exit of process is not noticed")
+	}
+	_ = cmd.Wait()
+	if code := cmd.ProcessState.ExitCode(); code != 3 {
+		t.Errorf("exit code %v, want 3", code)
+	}
+}
@@ -0,0 +1,24 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// processExited returns channel closed once process <p> exits
+func processExited(p *os.Process) <-chan struct{} {
+	exited := make(chan struct{})
+	goTracked(func() {
+		defer close(exited)
+		handle, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(p.Pid))
+		if err != nil {
+			return
+		}
+		defer windows.CloseHandle(handle)
+		_, _ = windows.WaitForSingleObject(handle, windows.INFINITE)
+	})
+	return exited
+}
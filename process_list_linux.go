@@ -0,0 +1,50 @@
+// +build linux
+
+package executor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listProcesses returns all running processes without children set
+func listProcesses() ([]ProcessInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []ProcessInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// Process may exit while listing
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// Format: pid (comm) state ppid ..., comm may contain spaces and parentheses
+		stat := string(data)
+		nameStart, nameEnd := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+		if nameStart < 0 || nameEnd < nameStart {
+			continue
+		}
+		fields := strings.Fields(stat[nameEnd+1:])
+		if len(fields) < 2 {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		rss, _ := processRSS(pid)
+		procs = append(procs, ProcessInfo{
+			PID:    pid,
+			PPID:   ppid,
+			Name:   stat[nameStart+1 : nameEnd],
+			Memory: rss,
+		})
+	}
+
+	return procs, nil
+}
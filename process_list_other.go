@@ -0,0 +1,39 @@
+// +build !linux,!windows
+
+package executor
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listProcesses returns all running processes without children set
+func listProcesses() ([]ProcessInfo, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,rss=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []ProcessInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		rss, _ := strconv.ParseUint(fields[2], 10, 64)
+		procs = append(procs, ProcessInfo{
+			PID:    pid,
+			PPID:   ppid,
+			Name:   strings.Join(fields[3:], " "),
+			Memory: rss * 1024,
+		})
+	}
+
+	return procs, nil
+}
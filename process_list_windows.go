@@ -0,0 +1,37 @@
+// +build windows
+
+package executor
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// listProcesses returns all running processes without children set
+func listProcesses() ([]ProcessInfo, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var procs []ProcessInfo
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		// Memory of protected processes can not be queried
+		rss, _ := processRSS(int(entry.ProcessID))
+		procs = append(procs, ProcessInfo{
+			PID:    int(entry.ProcessID),
+			PPID:   int(entry.ParentProcessID),
+			Name:   windows.UTF16ToString(entry.ExeFile[:]),
+			Memory: rss,
+		})
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+
+	return procs, nil
+}
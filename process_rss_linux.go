@@ -0,0 +1,39 @@
+// +build linux
+
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processRSS returns resident memory size of process with <pid> in bytes
+func processRSS(pid int) (uint64, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%v/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "VmRSS:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb * 1024, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	// Zombie processes have no VmRSS field
+	return 0, errors.New("process is not running")
+}
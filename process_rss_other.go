@@ -0,0 +1,22 @@
+// +build !linux,!windows
+
+package executor
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// processRSS returns resident memory size of process with <pid> in bytes
+func processRSS(pid int) (uint64, error) {
+	out, err := exec.Command("ps", "-o", "rss=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, err
+	}
+	kb, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return kb * 1024, nil
+}
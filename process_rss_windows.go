@@ -0,0 +1,54 @@
+// +build windows
+
+package executor
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is an exit code of running process (STILL_ACTIVE)
+const stillActive = 259
+
+var procGetProcessMemoryInfo = windows.NewLazySystemDLL("psapi.dll").NewProc("GetProcessMemoryInfo")
+
+// processMemoryCounters represents PROCESS_MEMORY_COUNTERS structure
+type processMemoryCounters struct {
+	CB                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// processRSS returns working set size of process with <pid> in bytes
+func processRSS(pid int) (uint64, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION|windows.PROCESS_VM_READ, false, uint32(pid))
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return 0, err
+	}
+	if exitCode != stillActive {
+		return 0, errors.New("process is not running")
+	}
+
+	var counters processMemoryCounters
+	counters.CB = uint32(unsafe.Sizeof(counters))
+	r, _, err := procGetProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.CB))
+	if r == 0 {
+		return 0, err
+	}
+	return uint64(counters.WorkingSetSize), nil
+}
@@ -0,0 +1,52 @@
+package executor
+
+// ProcessInfo represents a running process
+type ProcessInfo struct {
+	PID      int           // Process ID
+	PPID     int           // Parent process ID
+	Name     string        // Executable name
+	Memory   uint64        // Resident memory size in bytes
+	Children []ProcessInfo // Child processes
+}
+
+// Children returns tree of live descendants of process with <pid>
+func Children(pid int) ([]ProcessInfo, error) {
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	byParent := map[int][]ProcessInfo{}
+	for _, p := range procs {
+		if p.PID != p.PPID {
+			byParent[p.PPID] = append(byParent[p.PPID], p)
+		}
+	}
+
+	return buildProcessTree(pid, byParent, map[int]bool{pid: true}), nil
+}
+
+// buildProcessTree returns children of <pid> with their descendants.
+// <visited> guards against cycles caused by PID reuse
+func buildProcessTree(pid int, byParent map[int][]ProcessInfo, visited map[int]bool) []ProcessInfo {
+	var children []ProcessInfo
+	for _, child := range byParent[pid] {
+		if visited[child.PID] {
+			continue
+		}
+		visited[child.PID] = true
+		child.Children = buildProcessTree(child.PID, byParent, visited)
+		children = append(children, child)
+	}
+	return children
+}
+
+// flattenProcessTree returns all processes of <tree> in depth-first order
+func flattenProcessTree(tree []ProcessInfo) []ProcessInfo {
+	var list []ProcessInfo
+	for _, p := range tree {
+		list = append(list, p)
+		list = append(list, flattenProcessTree(p.Children)...)
+	}
+	return list
+}
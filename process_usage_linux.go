@@ -0,0 +1,57 @@
+// +build linux
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is a number of clock ticks per second (USER_HZ), used in /proc/<pid>/stat
+const clockTicks = 100
+
+// getProcessUsage returns cumulative CPU time and storage I/O of process with <pid>
+func getProcessUsage(pid int) (processUsage, error) {
+	var usage processUsage
+
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%v/stat", pid))
+	if err != nil {
+		return usage, err
+	}
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	// Fields after comm: state ppid pgrp session tty_nr tpgid flags minflt cminflt majflt cmajflt utime stime
+	if len(fields) < 13 {
+		return usage, errors.New("unexpected /proc/<pid>/stat format")
+	}
+	if fields[0] == "Z" {
+		return usage, errors.New("process is not running")
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	usage.cpu = time.Duration(utime+stime) * time.Second / clockTicks
+
+	// I/O accounting may be unavailable or forbidden
+	data, err = os.ReadFile(fmt.Sprintf("/proc/%v/io", pid))
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			value, _ := strconv.ParseUint(fields[1], 10, 64)
+			switch fields[0] {
+			case "read_bytes:":
+				usage.readBytes = value
+			case "write_bytes:":
+				usage.writeBytes = value
+			}
+		}
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,21 @@
+// +build !linux,!windows
+
+package executor
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// getProcessUsage returns cumulative CPU time of process with <pid>. Storage I/O is not available on this platform
+func getProcessUsage(pid int) (processUsage, error) {
+	var usage processUsage
+
+	out, err := exec.Command("ps", "-o", "time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return usage, err
+	}
+	usage.cpu, err = parsePsTime(strings.TrimSpace(string(out)))
+	return usage, err
+}
@@ -0,0 +1,59 @@
+// +build windows
+
+package executor
+
+import (
+	"errors"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetProcessIoCounters = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetProcessIoCounters")
+
+// ioCounters represents IO_COUNTERS structure
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+// getProcessUsage returns cumulative CPU time and I/O of process with <pid>
+func getProcessUsage(pid int) (processUsage, error) {
+	var usage processUsage
+
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return usage, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return usage, err
+	}
+	if exitCode != stillActive {
+		return usage, errors.New("process is not running")
+	}
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return usage, err
+	}
+	// Filetime of a duration is in 100-nanosecond ticks
+	ticks := uint64(kernel.HighDateTime)<<32 | uint64(kernel.LowDateTime)
+	ticks += uint64(user.HighDateTime)<<32 | uint64(user.LowDateTime)
+	usage.cpu = time.Duration(ticks * 100)
+
+	var counters ioCounters
+	if r, _, _ := procGetProcessIoCounters.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters))); r != 0 {
+		usage.readBytes = counters.ReadTransferCount
+		usage.writeBytes = counters.WriteTransferCount
+	}
+
+	return usage, nil
+}
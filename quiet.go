@@ -0,0 +1,131 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spinnerInterval is the interval between spinner frames
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnerFrames are characters of spinner animation
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// spinner renders status line of running quiet processes to StdErr
+type spinner struct {
+	mu       sync.Mutex
+	w        io.Writer
+	terminal bool
+	running  map[int]string // Labels of running processes by ID
+	nextID   int
+	frame    int
+	done     chan struct{}
+}
+
+// quietSpinner is the status line shared by all quiet processes
+var quietSpinner = &spinner{w: os.Stderr, terminal: isTerminal(os.Stderr), running: map[int]string{}}
+
+// add shows running process with <label> and returns its ID
+func (s *spinner) add(label string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.running[id] = label
+	if s.terminal && s.done == nil {
+		s.done = make(chan struct{})
+		done := s.done
+		goTracked(func() { s.run(done) })
+	}
+	s.render()
+	return id
+}
+
+// remove replaces process with <id> in status line with final <status> line
+func (s *spinner) remove(id int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+	if s.terminal {
+		fmt.Fprint(s.w, "\r\x1b[K")
+	}
+	fmt.Fprintln(s.w, status)
+	if len(s.running) == 0 && s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	s.render()
+}
+
+// run renders frames until <done> is closed
+func (s *spinner) run(done chan struct{}) {
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.frame++
+			s.render()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// render draws status line. Must be called with mutex locked
+func (s *spinner) render() {
+	if !s.terminal || len(s.running) == 0 {
+		return
+	}
+	labels := make([]string, 0, len(s.running))
+	for id := 0; id < s.nextID; id++ {
+		if label, ok := s.running[id]; ok {
+			labels = append(labels, label)
+		}
+	}
+	fmt.Fprintf(s.w, "\r\x1b[K%v %v", spinnerFrames[s.frame%len(spinnerFrames)], strings.Join(labels, ", "))
+}
+
+// startQuiet starts process described by <opts> showing only a status line, and prints its output if it fails
+func startQuiet(opts Options) Result {
+	opts.Quiet = false
+	capture := opts.Capture
+	opts.Print = false
+	opts.Capture = true
+	if !opts.Wait {
+		opts.Capture = capture
+		return start(opts)
+	}
+
+	label := strings.TrimSuffix(filepath.Base(opts.Command), filepath.Ext(opts.Command))
+	if len(opts.Args) > 0 {
+		label += " " + strings.Join(opts.Args, " ")
+	}
+	id := quietSpinner.add(label)
+	started := time.Now()
+	res := start(opts)
+	elapsed := time.Since(started).Round(10 * time.Millisecond)
+
+	if res.DoneOk {
+		quietSpinner.remove(id, fmt.Sprintf("ok   %v (%v)", label, elapsed))
+	} else {
+		quietSpinner.remove(id, fmt.Sprintf("FAIL %v (%v, exit code %v)", label, elapsed, res.ExitCode))
+		quietSpinner.mu.Lock()
+		fmt.Print(res.Output)
+		if res.Output != "" && !strings.HasSuffix(res.Output, "\n") {
+			fmt.Println()
+		}
+		quietSpinner.mu.Unlock()
+	}
+	if !capture {
+		res.Output = ""
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// posixSafe matches strings which need no quoting in POSIX shells
+	posixSafe = regexp.MustCompile(`^[A-Za-z0-9@%+=:,./_-]+$`)
+	// cmdMeta matches characters which are escaped with caret for cmd.exe
+	cmdMeta = regexp.MustCompile("([()\\][%!^\"`<>&|;, *?])")
+	// quoteBackslashes matches backslashes followed by double quote
+	quoteBackslashes = regexp.MustCompile(`(\\*)"`)
+	// trailingBackslashes matches backslashes at the end of string
+	trailingBackslashes = regexp.MustCompile(`(\\*)$`)
+)
+
+// QuotePosix returns <s> quoted to be used as a single word in POSIX shell command line
+func QuotePosix(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if posixSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// QuoteCmdExe returns <s> quoted to be used as a single argument in cmd.exe command line.
+//
+// Argument is first quoted for CommandLineToArgvW (backslashes preceding quotes are doubled),
+// then every cmd.exe metacharacter, double quotes included, is escaped with caret.
+// Line breaks can not be passed through cmd.exe and are left as is
+func QuoteCmdExe(s string) string {
+	s = quoteBackslashes.ReplaceAllString(s, `$1$1\"`)
+	s = trailingBackslashes.ReplaceAllString(s, `$1$1`)
+	s = `"` + s + `"`
+	return cmdMeta.ReplaceAllString(s, `^$1`)
+}
+
+// QuotePowerShell returns <s> quoted to be used as a single argument in PowerShell command line.
+//
+// Single quoted strings are not expanded by PowerShell, single quotes inside are doubled.
+// Typographic single quotes are treated as single quotes by PowerShell, so they are doubled too
+func QuotePowerShell(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			sb.WriteRune(r)
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
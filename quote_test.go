@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os/exec"
+	"runtime"
+	"testing"
+	"unicode/utf16"
+)
+
+// quoteCases are strings with characters special to some of the shells
+var quoteCases = []string{
+	"",
+	"abc",
+	"a b",
+	"it's",
+	"a'b'c",
+	`a"b`,
+	`a\b`,
+	`a\"b`,
+	`trail\`,
+	`trail\\`,
+	"100%",
+	"%PATH%",
+	"^caret",
+	"a&b|c",
+	"!x!",
+	"tab\tx",
+	"new\nline",
+	"‘typo’",
+	"$HOME",
+	"`cmd`",
+	"$(cmd)",
+	"-flag=1",
+	"@%+=:,./_-",
+	"*?[]",
+	"(a);<b>",
+}
+
+func TestQuotePosix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"abc", "abc"},
+		{"-flag=1", "-flag=1"},
+		{"@%+=:,./_-", "@%+=:,./_-"},
+		{"a b", "'a b'"},
+		{"it's", `'it'"'"'s'`},
+		{`a"b`, `'a"b'`},
+		{`a\b`, `'a\b'`},
+		{"$HOME", "'$HOME'"},
+		{"`cmd`", "'`cmd`'"},
+		{"new\nline", "'new\nline'"},
+	}
+	for _, tt := range tests {
+		if got := QuotePosix(tt.in); got != tt.want {
+			t.Errorf("QuotePosix(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuotePosixRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell is not available on Windows")
+	}
+	for _, s := range quoteCases {
+		out, err := exec.Command("/bin/sh", "-c", "printf '%s' "+QuotePosix(s)).Output()
+		if err != nil {
+			t.Fatalf("sh with %q: %v", s, err)
+		}
+		if string(out) != s {
+			t.Errorf("sh printed %q, want %q", out, s)
+		}
+	}
+}
+
+func TestQuoteCmdExe(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `^"^"`},
+		{"abc", `^"abc^"`},
+		{"a b", `^"a^ b^"`},
+		{"it's", `^"it's^"`},
+		{`a"b`, `^"a\^"b^"`},
+		{`a\b`, `^"a\b^"`},
+		{`a\"b`, `^"a\\\^"b^"`},
+		{`trail\`, `^"trail\\^"`},
+		{`trail\\`, `^"trail\\\\^"`},
+		{"100%", `^"100^%^"`},
+		{"^caret", `^"^^caret^"`},
+		{"a&b|c", `^"a^&b^|c^"`},
+		{"!x!", `^"^!x^!^"`},
+		{"(a);<b>", `^"^(a^)^;^<b^>^"`},
+		{"*?[]", `^"^*^?^[^]^"`},
+		{"`cmd`", "^\"^`cmd^`^\""},
+		{"a,b", `^"a^,b^"`},
+		{"new\nline", "^\"new\nline^\""},
+	}
+	for _, tt := range tests {
+		if got := QuoteCmdExe(tt.in); got != tt.want {
+			t.Errorf("QuoteCmdExe(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuotePowerShell(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"abc", "'abc'"},
+		{"a b", "'a b'"},
+		{"it's", "'it''s'"},
+		{"a'b'c", "'a''b''c'"},
+		{`a"b`, `'a"b'`},
+		{"$HOME", "'$HOME'"},
+		{"`cmd`", "'`cmd`'"},
+		{"$(cmd)", "'$(cmd)'"},
+		{"‘typo’", "'‘‘typo’’'"},
+		{"‚low‛", "'‚‚low‛‛'"},
+	}
+	for _, tt := range tests {
+		if got := QuotePowerShell(tt.in); got != tt.want {
+			t.Errorf("QuotePowerShell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuotePowerShellRoundTrip(t *testing.T) {
+	shell, err := exec.LookPath("pwsh")
+	if err != nil {
+		if shell, err = exec.LookPath("powershell"); err != nil {
+			t.Skip("PowerShell is not installed")
+		}
+	}
+	for _, s := range quoteCases {
+		// Encoded, as Windows PowerShell drops double quotes of command given as argument
+		script := "[Console]::OutputEncoding = New-Object Text.UTF8Encoding $false; " +
+			"[Console]::Out.Write(" + QuotePowerShell(s) + ")"
+		units := utf16.Encode([]rune(script))
+		encoded := make([]byte, 2*len(units))
+		for i, c := range units {
+			binary.LittleEndian.PutUint16(encoded[2*i:], c)
+		}
+		out, err := exec.Command(shell, "-NoProfile", "-NonInteractive", "-EncodedCommand",
+			base64.StdEncoding.EncodeToString(encoded)).Output()
+		if err != nil {
+			t.Fatalf("PowerShell with %q: %v", s, err)
+		}
+		if string(out) != s {
+			t.Errorf("PowerShell printed %q, want %q", out, s)
+		}
+	}
+}
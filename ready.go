@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"net"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ReadyCondition reports whether <line> of output means the process is ready, like a server printing
+// "Listening on :8080"
+type ReadyCondition func(line string) bool
+
+// ReadyOnMatch returns ReadyCondition satisfied by line matching <re>
+func ReadyOnMatch(re *regexp.Regexp) ReadyCondition {
+	return re.MatchString
+}
+
+// readyProbeInterval is the interval between checks of Options.ReadyAddress
+const readyProbeInterval = 100 * time.Millisecond
+
+// defaultReadyAddressTimeout is the time to wait for Options.ReadyAddress to open if Options.ReadyTimeout is not set
+const defaultReadyAddressTimeout = 30 * time.Second
+
+// readyWaiter tracks whether process reported readiness set with Options.ReadyWhen
+type readyWaiter struct {
+	once  sync.Once
+	ready chan struct{}
+}
+
+// newReadyWaiter returns waiter of process not ready yet
+func newReadyWaiter() *readyWaiter {
+	return &readyWaiter{ready: make(chan struct{})}
+}
+
+// set marks process as ready. Safe to call more than once
+func (w *readyWaiter) set() {
+	w.once.Do(func() { close(w.ready) })
+}
+
+// isReady returns true if process is ready. Safe to call on nil waiter
+func (w *readyWaiter) isReady() bool {
+	if w == nil {
+		return false
+	}
+	select {
+	case <-w.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// wrapOnLine returns line callback checking lines against <cond> before passing them to <onLine> (optional)
+func (w *readyWaiter) wrapOnLine(cond ReadyCondition, onLine func(l string, p *os.Process)) func(l string, p *os.Process) {
+	return func(l string, p *os.Process) {
+		if !w.isReady() && cond(l) {
+			w.set()
+		}
+		if onLine != nil {
+			onLine(l, p)
+		}
+	}
+}
+
+// probe marks process as ready once <address> of <network> ("tcp" if empty) is open, checking it until <done>
+// is closed. TCP address is open once it accepts connections, UDP address once it is bound on this machine
+func (w *readyWaiter) probe(network string, address string, done <-chan struct{}) {
+	if network == "" {
+		network = "tcp"
+	}
+	ticker := time.NewTicker(readyProbeInterval)
+	defer ticker.Stop()
+	for {
+		if portOpen(network, address) {
+			w.set()
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// portOpen returns true if <address> of <network> is open
+func portOpen(network string, address string) bool {
+	switch network {
+	case "udp", "udp4", "udp6":
+		// Datagrams are not acknowledged, so the port is checked by trying to take it. Other errors, like missing
+		// permission or invalid address, do not mean anyone is listening
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			return addrInUse(err)
+		}
+		_ = conn.Close()
+		return false
+	default:
+		conn, err := net.DialTimeout(network, address, readyProbeInterval)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+}
+
+// wait blocks until process is ready, <done> is closed or <timeout> passes (0 = no timeout).
+// Returns true if process is ready
+func (w *readyWaiter) wait(timeout time.Duration, done <-chan struct{}) bool {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-w.ready:
+	case <-done:
+	case <-expired:
+	}
+	return w.isReady()
+}
@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// CommandTemplate represents a named command definition with parameters.
+//
+// Command and each of Args are text/template templates rendered with parameters, like "--out={{.dir}}".
+// Every argument is rendered separately, so parameter values never split into several arguments
+type CommandTemplate struct {
+	Command  string                               // Command to run
+	Args     []string                             // Command arguments
+	Defaults map[string]string                    // Default values of parameters
+	Required []string                             // Parameters which must be set
+	Validate func(params map[string]string) error // Function checking parameters, with defaults applied, before start
+	Options  Options                              // Base options, Command and Args of which are replaced
+}
+
+// Registry represents a set of named command templates, safe for concurrent use
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]CommandTemplate
+}
+
+// NewRegistry returns empty Registry
+func NewRegistry() *Registry {
+	return &Registry{templates: map[string]CommandTemplate{}}
+}
+
+// Register adds template <t> with <name>. Returns error if the name is already registered
+func (r *Registry) Register(name string, t CommandTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[name]; ok {
+		return fmt.Errorf("command %q is already registered", name)
+	}
+	r.templates[name] = t
+	return nil
+}
+
+// Names returns sorted names of registered templates
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options returns options to start command registered with <name> with <params>
+func (r *Registry) Options(name string, params map[string]string) (Options, error) {
+	r.mu.RLock()
+	t, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Options{}, fmt.Errorf("command %q is not registered", name)
+	}
+
+	values := map[string]string{}
+	for k, v := range t.Defaults {
+		values[k] = v
+	}
+	for k, v := range params {
+		values[k] = v
+	}
+	for _, k := range t.Required {
+		if _, ok := values[k]; !ok {
+			return Options{}, fmt.Errorf("command %q: parameter %q is required", name, k)
+		}
+	}
+	if t.Validate != nil {
+		if err := t.Validate(values); err != nil {
+			return Options{}, fmt.Errorf("command %q: %w", name, err)
+		}
+	}
+
+	opts := t.Options
+	var err error
+	if opts.Command, err = renderArg(t.Command, values); err != nil {
+		return Options{}, fmt.Errorf("command %q: %w", name, err)
+	}
+	opts.Args = make([]string, 0, len(t.Args))
+	for _, arg := range t.Args {
+		rendered, err := renderArg(arg, values)
+		if err != nil {
+			return Options{}, fmt.Errorf("command %q: %w", name, err)
+		}
+		opts.Args = append(opts.Args, rendered)
+	}
+	return opts, nil
+}
+
+// Start starts command registered with <name> with <params>
+func (r *Registry) Start(name string, params map[string]string) (Result, error) {
+	opts, err := r.Options(name, params)
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+	return Start(opts), nil
+}
+
+// renderArg renders template <text> with <values>. Unknown parameters are an error
+func renderArg(text string, values map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("arg").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, values); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"fmt"
+)
+
+// ReplayOptions represents overrides of recorded options for replaying an execution
+type ReplayOptions struct {
+	Env   []string // Additional environment variables in "KEY=VALUE" form, overriding recorded environment
+	Dir   string   // Working directory (recorded one if not set)
+	Print bool     // Print output to console?
+}
+
+// ReplayComparison represents differences between recorded and replayed executions
+type ReplayComparison struct {
+	Record     HistoryRecord // Recorded execution
+	ExitCodeOk bool          // Exit codes are the same?
+	OutputDiff string        // Unified diff of recorded and replayed output, empty if they are the same
+}
+
+// Replay re-runs execution recorded with <id> using its command, arguments, working directory and environment,
+// overridden by <ropts>, waits for it and compares its exit code and output with the recorded ones.
+//
+// Recorded output is truncated, so the replayed output is truncated the same way before comparison
+func (h *History) Replay(id int64, ropts ReplayOptions) (Result, ReplayComparison, error) {
+	rec, ok := h.Get(id)
+	if !ok {
+		return Result{ExitCode: -1}, ReplayComparison{}, fmt.Errorf("history record %v not found", id)
+	}
+
+	opts := Options{
+		Command:      rec.Command,
+		Args:         rec.Args,
+		Dir:          rec.Dir,
+		EnvFiles:     rec.EnvFiles,
+		EnvSpec:      rec.EnvSpec,
+		Env:          append(append([]string(nil), rec.Env...), ropts.Env...),
+		NoInheritEnv: rec.NoInheritEnv,
+		Print:        ropts.Print,
+		Capture:      true,
+		Wait:         true,
+	}
+	if ropts.Dir != "" {
+		opts.Dir = ropts.Dir
+	}
+
+	res := Start(opts)
+	if !res.StartOk {
+		return res, ReplayComparison{Record: rec}, res.Err
+	}
+
+	output := res.Output
+	if h.maxOutput > 0 {
+		output = truncateHead(output, h.maxOutput)
+	} else {
+		output = ""
+	}
+	cmp := ReplayComparison{
+		Record:     rec,
+		ExitCodeOk: res.ExitCode == rec.ExitCode,
+		OutputDiff: unifiedDiff(fmt.Sprintf("record %v", id), "replay", rec.Output, output),
+	}
+	return res, cmp, nil
+}
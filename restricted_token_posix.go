@@ -0,0 +1,13 @@
+// +build !windows
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// restrictToken returns error as restricted tokens are a Windows feature
+func restrictToken(cmd *exec.Cmd) (func(), error) {
+	return func() {}, errors.New("restricted token is only supported on Windows")
+}
@@ -0,0 +1,67 @@
+// +build windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	disableMaxPrivilege = 0x1 // CreateRestrictedToken flag DISABLE_MAX_PRIVILEGE
+	luaToken            = 0x4 // CreateRestrictedToken flag LUA_TOKEN
+
+	lowIntegritySid = "S-1-16-4096"
+)
+
+var procCreateRestrictedToken = windows.NewLazySystemDLL("advapi32.dll").NewProc("CreateRestrictedToken")
+
+// restrictToken makes <cmd> run with a restricted copy of the current process token:
+// all privileges except SeChangeNotifyPrivilege removed, administrator group filtered and integrity level lowered.
+//
+// Returned function closes the token and should be called once the process started
+func restrictToken(cmd *exec.Cmd) (func(), error) {
+	noop := func() {}
+
+	var current windows.Token
+	access := uint32(windows.TOKEN_DUPLICATE | windows.TOKEN_ASSIGN_PRIMARY | windows.TOKEN_QUERY | windows.TOKEN_ADJUST_DEFAULT)
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), access, &current); err != nil {
+		return noop, err
+	}
+	defer current.Close()
+
+	var restricted windows.Token
+	r, _, err := procCreateRestrictedToken.Call(
+		uintptr(current),
+		disableMaxPrivilege|luaToken,
+		0, 0, 0, 0, 0, 0,
+		uintptr(unsafe.Pointer(&restricted)),
+	)
+	if r == 0 {
+		return noop, err
+	}
+
+	sid, err := windows.StringToSid(lowIntegritySid)
+	if err != nil {
+		restricted.Close()
+		return noop, err
+	}
+	label := windows.Tokenmandatorylabel{
+		Label: windows.SIDAndAttributes{Sid: sid, Attributes: windows.SE_GROUP_INTEGRITY},
+	}
+	err = windows.SetTokenInformation(restricted, windows.TokenIntegrityLevel, (*byte)(unsafe.Pointer(&label)), label.Size())
+	if err != nil {
+		restricted.Close()
+		return noop, err
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Token = syscall.Token(restricted)
+
+	return func() { restricted.Close() }, nil
+}
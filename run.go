@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"context"
+)
+
+// Run runs <command> with <args> until it exits or <ctx> is done and returns its StdOut.
+// Output is not printed. Returns Result.Err if process did not finish successfully, like ExitError with StdErr tail
+func Run(ctx context.Context, command string, args ...string) (string, error) {
+	res := Start(Options{
+		Command:         command,
+		Args:            args,
+		Wait:            true,
+		SeparateCapture: true,
+		Context:         ctx,
+	})
+	if !res.StartOk {
+		return "", res.Err
+	}
+	return res.Stdout, res.Err
+}
+
+// Output runs a process described by <opts>, waits for it and returns its StdOut, like exec.Cmd.Output.
+// Returns Result.Err if process did not finish successfully, like ExitError with StdErr tail
+func Output(opts Options) ([]byte, error) {
+	opts.Wait = true
+	opts.SeparateCapture = true
+	res := Start(opts)
+	if !res.StartOk {
+		return nil, res.Err
+	}
+	return []byte(res.Stdout), res.Err
+}
+
+// CombinedOutput runs a process described by <opts>, waits for it and returns its StdOut and StdErr, like
+// exec.Cmd.CombinedOutput. Returns Result.Err if process did not finish successfully
+func CombinedOutput(opts Options) ([]byte, error) {
+	opts.Wait = true
+	opts.Capture = true
+	res := Start(opts)
+	if !res.StartOk {
+		return nil, res.Err
+	}
+	return []byte(res.Output), res.Err
+}
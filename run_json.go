@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RunJSON runs a process described by <opts> until it exits or <ctx> is done and decodes its StdOut as JSON into <v>,
+// like json.Unmarshal does. Returns Result.Err if process did not finish successfully or ErrInvalidJSON if StdOut
+// can not be decoded
+func RunJSON(ctx context.Context, opts Options, v interface{}) (Result, error) {
+	opts.Context = ctx
+	opts.Wait = true
+	opts.SeparateCapture = true
+	res := Start(opts)
+	if res.Err != nil {
+		return res, res.Err
+	}
+	if err := json.Unmarshal([]byte(res.Stdout), v); err != nil {
+		return res, fmt.Errorf("%w: output of %v: %v", ErrInvalidJSON, opts.Command, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"runtime"
+	"testing"
+)
+
+func TestRunPolicyDenied(t *testing.T) {
+	SetPolicy(ListPolicy{Deny: []string{"*"}})
+	defer SetPolicy(nil)
+	_, err := Run(context.Background(), "go", "version")
+	if !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("got %v, want ErrPolicyDenied", err)
+	}
+}
+
+func TestOutputMatchedFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell is not available on Windows")
+	}
+	_, err := Output(Options{
+		Command:             "/bin/sh",
+		Args:                []string{"-c", "echo FATAL: broken"},
+		FailIfOutputMatches: []*regexp.Regexp{regexp.MustCompile(`^FATAL`)},
+	})
+	if !errors.Is(err, ErrOutputMatched) {
+		t.Errorf("got %v, want ErrOutputMatched", err)
+	}
+}
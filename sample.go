@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// OutputSample represents the first and the last lines of output, with the number of lines between them
+type OutputSample struct {
+	Head    []string // First lines, up to Options.SampleHead
+	Tail    []string // Last lines after Head, up to Options.SampleTail
+	Skipped int      // Number of lines between Head and Tail which were not kept
+}
+
+// String returns lines of the sample, with skipped lines replaced by a note
+func (s OutputSample) String() string {
+	lines := append([]string(nil), s.Head...)
+	if s.Skipped > 0 {
+		lines = append(lines, fmt.Sprintf("... %v lines skipped ...", s.Skipped))
+	}
+	lines = append(lines, s.Tail...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// lineSampler keeps the first and the last lines passed to it
+type lineSampler struct {
+	mu       sync.Mutex
+	headSize int
+	tailSize int
+	head     []string
+	tail     []string // Ring buffer of the last lines
+	next     int      // Index in tail to write the next line to, once it is full
+	skipped  int
+}
+
+// newLineSampler returns sampler keeping <head> first and <tail> last lines
+func newLineSampler(head int, tail int) *lineSampler {
+	return &lineSampler{headSize: head, tailSize: tail}
+}
+
+// add adds line <l>
+func (s *lineSampler) add(l string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case len(s.head) < s.headSize:
+		s.head = append(s.head, l)
+	case s.tailSize <= 0:
+		s.skipped++
+	case len(s.tail) < s.tailSize:
+		s.tail = append(s.tail, l)
+	default:
+		s.tail[s.next] = l
+		s.next = (s.next + 1) % s.tailSize
+		s.skipped++
+	}
+}
+
+// wrapOnLine returns line callback adding lines to the sampler before passing them to <onLine> (optional)
+func (s *lineSampler) wrapOnLine(onLine func(l string, p *os.Process)) func(l string, p *os.Process) {
+	return func(l string, p *os.Process) {
+		s.add(l)
+		if onLine != nil {
+			onLine(l, p)
+		}
+	}
+}
+
+// sample returns kept lines. Safe to call on nil sampler
+func (s *lineSampler) sample() OutputSample {
+	if s == nil {
+		return OutputSample{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tail := make([]string, 0, len(s.tail))
+	tail = append(tail, s.tail[s.next:]...)
+	tail = append(tail, s.tail[:s.next]...)
+	return OutputSample{
+		Head:    append([]string(nil), s.head...),
+		Tail:    tail,
+		Skipped: s.skipped,
+	}
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Sandbox represents options to run process inside bubblewrap or firejail sandbox
+type Sandbox struct {
+	Tool             string   // Sandbox tool to use: "bwrap", "firejail" or empty to pick the first one available
+	Binds            []string // Paths to make writable inside sandbox
+	ReadOnlyBinds    []string // Paths to make read-only inside sandbox
+	ReadOnlyRoot     bool     // Make root filesystem read-only?
+	TmpfsHome        bool     // Mount empty tmpfs over home directory?
+	AllowUnsandboxed bool     // Run without sandbox if no sandbox tool is available? Otherwise fail to start with ErrNoSandboxTool
+}
+
+// ErrNoSandboxTool is reported when no sandbox tool is available, it wraps ErrUnsupported
+var ErrNoSandboxTool = fmt.Errorf("%w: no sandbox tool (bwrap, firejail) is available", ErrUnsupported)
+
+// wrap returns command and arguments which run <command> with <args> in <dir> inside sandbox
+func (s Sandbox) wrap(command string, args []string, dir string) (string, []string, error) {
+	tools := []string{"bwrap", "firejail"}
+	if s.Tool != "" {
+		tools = []string{s.Tool}
+	}
+
+	for _, tool := range tools {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			continue
+		}
+		switch tool {
+		case "bwrap":
+			return path, append(s.bwrapArgs(dir), append([]string{"--", command}, args...)...), nil
+		case "firejail":
+			return path, append(s.firejailArgs(), append([]string{"--", command}, args...)...), nil
+		default:
+			return "", nil, errors.New("unknown sandbox tool: " + tool)
+		}
+	}
+
+	return "", nil, ErrNoSandboxTool
+}
+
+// bwrapArgs returns bubblewrap arguments for running in <dir>
+func (s Sandbox) bwrapArgs(dir string) []string {
+	var args []string
+	if s.ReadOnlyRoot {
+		args = append(args, "--ro-bind", "/", "/")
+	} else {
+		args = append(args, "--bind", "/", "/")
+	}
+	args = append(args, "--dev", "/dev", "--proc", "/proc", "--die-with-parent")
+	if s.TmpfsHome {
+		if home, err := os.UserHomeDir(); err == nil {
+			args = append(args, "--tmpfs", home)
+		}
+	}
+	for _, path := range s.ReadOnlyBinds {
+		args = append(args, "--ro-bind", path, path)
+	}
+	for _, path := range s.Binds {
+		args = append(args, "--bind", path, path)
+	}
+	if dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			args = append(args, "--chdir", abs)
+		}
+	}
+	return args
+}
+
+// firejailArgs returns firejail arguments
+func (s Sandbox) firejailArgs() []string {
+	args := []string{"--quiet"}
+	if s.ReadOnlyRoot {
+		args = append(args, "--read-only=/")
+	}
+	if s.TmpfsHome {
+		args = append(args, "--private")
+	}
+	for _, path := range s.ReadOnlyBinds {
+		args = append(args, "--read-only="+path)
+	}
+	for _, path := range s.Binds {
+		args = append(args, "--read-write="+path)
+	}
+	return args
+}
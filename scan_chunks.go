@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// chunkBufferPool holds read buffers of chunk scanning
+var chunkBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 32*1024)
+		return &buf
+	},
+}
+
+// scanChunks reads <stream> from <r> in large chunks until EOF, handling whole chunks at once instead of
+// separate characters. Chunks are cut at character boundaries, so printed and captured output is the same
+// as with scanning by characters
+func (s *outputScanner) scanChunks(r io.Reader, stream byte) {
+	buf := chunkBufferPool.Get().(*[]byte)
+	defer chunkBufferPool.Put(buf)
+
+	// Length of incomplete character at the end of previous chunk, kept at the start of buffer
+	pending := 0
+	// Unterminated line of this stream
+	var lineBuf bytes.Buffer
+	pendingCR := false
+	var offset int64
+
+	// Text for OnChar accumulated since the last tick
+	var charBuf bytes.Buffer
+	if s.opts.OnChar != nil && s.opts.CoalesceInterval > 0 {
+		stop := s.coalesce(&charBuf)
+		defer stop()
+	}
+
+	for {
+		n, err := r.Read((*buf)[pending:])
+		data := (*buf)[:pending+n]
+		cut := len(data)
+		if err == nil {
+			cut = completeRunes(data)
+		}
+		chunk := data[:cut]
+
+		if len(chunk) > 0 {
+			if !utf8.Valid(chunk) {
+				chunk = s.sanitizeUTF8(chunk, stream, offset)
+			}
+			offset += int64(cut)
+			text := chunk
+			if s.opts.NormalizeNewlines {
+				text = normalizeNewlines(chunk, &pendingCR)
+			}
+
+			s.mu.Lock()
+			if s.opts.Print {
+				s.printer.writeStream(stream, chunk)
+			}
+			if s.opts.Capture {
+				s.outSb.Write(text)
+			}
+			if s.opts.SeparateCapture {
+				if stream == StreamStdout {
+					s.stdoutSb.Write(text)
+				} else {
+					s.stderrSb.Write(text)
+				}
+			}
+			if s.opts.OnChar != nil {
+				if s.opts.CoalesceInterval > 0 {
+					charBuf.Write(chunk)
+				} else {
+					stopWatch := s.watchCallback("OnChar")
+					s.opts.OnChar(string(chunk), s.cmd.Process)
+					stopWatch()
+				}
+			}
+			if s.opts.OnLine != nil {
+				s.emitLines(text, &lineBuf)
+			}
+			s.mu.Unlock()
+		}
+
+		pending = copy(*buf, data[cut:])
+		if err != nil {
+			return
+		}
+	}
+}
+
+// coalesce starts passing text accumulated in <charBuf> to OnChar callback every Options.CoalesceInterval.
+// Returns function stopping it and passing the rest of text
+func (s *outputScanner) coalesce(charBuf *bytes.Buffer) func() {
+	flush := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if charBuf.Len() == 0 {
+			return
+		}
+		stopWatch := s.watchCallback("OnChar")
+		s.opts.OnChar(charBuf.String(), s.cmd.Process)
+		stopWatch()
+		charBuf.Reset()
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	goTracked(func() {
+		defer close(exited)
+		ticker := time.NewTicker(s.opts.CoalesceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				flush()
+			}
+		}
+	})
+
+	return func() {
+		close(done)
+		<-exited
+		flush()
+	}
+}
+
+// emitLines calls OnLine callback for each line of <text> terminated by CR or LF, continuing unterminated line
+// of previous chunk from <lineBuf> and leaving unterminated end in it. Must be called with mutex locked
+func (s *outputScanner) emitLines(text []byte, lineBuf *bytes.Buffer) {
+	for {
+		idx := bytes.IndexAny(text, "\r\n")
+		if idx < 0 {
+			lineBuf.Write(text)
+			return
+		}
+		lineBuf.Write(text[:idx])
+		stopWatch := s.watchCallback("OnLine")
+		s.opts.OnLine(lineBuf.String(), s.cmd.Process)
+		stopWatch()
+		lineBuf.Reset()
+		text = text[idx+1:]
+	}
+}
+
+// sanitizeUTF8 returns copy of <p> at <offset> of <stream>, with invalid bytes replaced or removed as set by
+// Options.InvalidBytes
+func (s *outputScanner) sanitizeUTF8(p []byte, stream byte, offset int64) []byte {
+	out := make([]byte, 0, len(p)+8)
+	for i := 0; i < len(p); {
+		r, width := utf8.DecodeRune(p[i:])
+		if r == utf8.RuneError && width == 1 {
+			s.invalidByte(stream, offset+int64(i))
+			if s.opts.InvalidBytes != InvalidSkip {
+				out = append(out, replacementChar...)
+			}
+		} else {
+			out = append(out, p[i:i+width]...)
+		}
+		i += width
+	}
+	return out
+}
+
+// completeRunes returns length of <p> without incomplete UTF-8 character at the end
+func completeRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return len(p)
+			}
+			return i
+		}
+	}
+	return len(p)
+}
+
+// normalizeNewlines returns <p> with CRLF and CR converted into LF. <pendingCR> carries state between chunks:
+// LF following CR at the end of previous chunk is dropped
+func normalizeNewlines(p []byte, pendingCR *bool) []byte {
+	if bytes.IndexByte(p, '\r') < 0 && !(*pendingCR && len(p) > 0 && p[0] == '\n') {
+		*pendingCR = false
+		return p
+	}
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch {
+		case b == '\r':
+			out = append(out, '\n')
+			*pendingCR = true
+		case b == '\n' && *pendingCR:
+			*pendingCR = false
+		default:
+			*pendingCR = false
+			out = append(out, b)
+		}
+	}
+	return out
+}
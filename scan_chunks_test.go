@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// scanOutput scans <r> as StdOut with <opts> and returns the result
+func scanOutput(opts Options, r io.Reader) Result {
+	s := newOutputScanner(opts, &exec.Cmd{}, r, nil)
+	s.start()
+	s.wait()
+	var res Result
+	s.fill(&res)
+	return res
+}
+
+// benchmarkOutput returns output of <lines> lines with multi-byte characters
+func benchmarkOutput(lines int) []byte {
+	var sb strings.Builder
+	for i := 0; i < lines; i++ {
+		sb.WriteString("compiling package ✓ módulo 模块 with some longer text to fill the line\n")
+	}
+	return []byte(sb.String())
+}
+
+func TestScanChunksMatchesRunes(t *testing.T) {
+	data := []byte("first ✓ line\r\nsecond 模块\rthird\n\nunterminated é")
+	var chunkLines, runeLines []string
+	var chars strings.Builder
+
+	// Characters split between reads are joined by chunk scanning
+	chunked := scanOutput(Options{
+		Capture: true,
+		OnLine:  func(line string, _ *os.Process) { chunkLines = append(chunkLines, line) },
+	}, iotest.OneByteReader(bytes.NewReader(data)))
+	byRunes := scanOutput(Options{
+		Capture: true,
+		OnLine:  func(line string, _ *os.Process) { runeLines = append(runeLines, line) },
+		OnChar:  func(c string, _ *os.Process) { chars.WriteString(c) },
+	}, bytes.NewReader(data))
+
+	if chunked.Output != string(data) {
+		t.Errorf("chunk scanning captured %q, want %q", chunked.Output, data)
+	}
+	if byRunes.Output != string(data) {
+		t.Errorf("rune scanning captured %q, want %q", byRunes.Output, data)
+	}
+	if chars.String() != string(data) {
+		t.Errorf("OnChar got %q, want %q", chars.String(), data)
+	}
+	if strings.Join(chunkLines, "|") != strings.Join(runeLines, "|") {
+		t.Errorf("chunk scanning passed lines %q, rune scanning passed %q", chunkLines, runeLines)
+	}
+}
+
+func TestScanChunksCoalescedOnChar(t *testing.T) {
+	data := benchmarkOutput(100)
+	var chars strings.Builder
+	scanOutput(Options{
+		OnChar:           func(c string, _ *os.Process) { chars.WriteString(c) },
+		CoalesceInterval: time.Millisecond,
+	}, iotest.HalfReader(bytes.NewReader(data)))
+	if chars.String() != string(data) {
+		t.Errorf("coalesced OnChar got %v bytes, want %v", chars.Len(), len(data))
+	}
+}
+
+// BenchmarkScanChunks scans output in pooled chunks, making strings only for lines
+func BenchmarkScanChunks(b *testing.B) {
+	data := benchmarkOutput(10000)
+	opts := Options{Capture: true, OnLine: func(string, *os.Process) {}}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		scanOutput(opts, bytes.NewReader(data))
+	}
+}
+
+// BenchmarkScanRunes scans output character by character, as OnChar without coalescing requires
+func BenchmarkScanRunes(b *testing.B) {
+	data := benchmarkOutput(10000)
+	opts := Options{Capture: true, OnLine: func(string, *os.Process) {}, OnChar: func(string, *os.Process) {}}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		scanOutput(opts, bytes.NewReader(data))
+	}
+}
+
+// BenchmarkScanChunksCoalescedOnChar passes characters to OnChar in batches, scanning output in chunks
+func BenchmarkScanChunksCoalescedOnChar(b *testing.B) {
+	data := benchmarkOutput(10000)
+	opts := Options{Capture: true, OnChar: func(string, *os.Process) {}, CoalesceInterval: 10 * time.Millisecond}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		scanOutput(opts, bytes.NewReader(data))
+	}
+}
@@ -0,0 +1,499 @@
+// Package schtask provides executor backend running commands with Windows Task Scheduler, which is the supported way
+// for services to launch processes in interactive session or with highest privileges of another account.
+//
+// Register it to use with Options.Backend:
+//
+//	executor.RegisterBackend("schtask", &schtask.Backend{User: "SYSTEM", Highest: true})
+package schtask
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SCP002/executor"
+)
+
+// defaultPollInterval is the interval between checks whether task finished, if Backend.PollInterval is not set
+const defaultPollInterval = 250 * time.Millisecond
+
+// defaultStartTimeout is the time allotted for task to start running, if Backend.StartTimeout is not set
+const defaultStartTimeout = 30 * time.Second
+
+// statusInterval is the interval between queries of task status, which are slower than checks of exit code file
+const statusInterval = 2 * time.Second
+
+// Last Result of task which is not finished, as reported by schtasks
+const (
+	resultRunning   = 0x41301 // SCHED_S_TASK_RUNNING
+	resultHasNotRun = 0x41303 // SCHED_S_TASK_HAS_NOT_RUN
+	resultQueued    = 0x41325 // SCHED_S_TASK_QUEUED
+)
+
+// errNotStarted is returned by task.wait if task did not start running
+var errNotStarted = errors.New("task did not start running")
+
+// Columns of verbose CSV output of "schtasks /Query", their names are localized
+const (
+	columnLastResult = 6
+	columnsCount     = 7
+)
+
+// Backend runs commands as transient scheduled tasks with schtasks.exe: task running a batch file is registered, run,
+// waited for and deleted.
+//
+// StdOut and StdErr are merged and redirected into a file, which is printed, captured and passed to Options.OnLine
+// (with nil process) once the task finished. Options.Dir is the working directory of the task, Options.Timeout,
+// Options.TimeoutDuration and Options.Context end the task. Task which did not start running in StartTimeout, like
+// interactive one while the user is logged off, is ended too.
+// Options changing the process, its input, or how its output is processed fail with executor.ErrUnsupported.
+//
+// Task with Password is registered with PowerShell reading the password from StdIn, as schtasks.exe takes it only
+// on command line, readable by other processes
+type Backend struct {
+	User         string        // Account to run task as, like "SYSTEM" or "DOMAIN\user" (the current user if not set)
+	Password     string        // Password of User, if it is not a built-in account and Interactive is not set
+	Highest      bool          // Run with highest privileges of the account, elevated without UAC prompt?
+	Interactive  bool          // Run in interactive session of User while they are logged on, so windows are visible?
+	TempDir      string        // Directory for batch and output files readable by the task account (system temporary directory if not set)
+	PollInterval time.Duration // Interval between checks whether task finished (250 ms if not set)
+	StartTimeout time.Duration // Time allotted for task to start running before it is ended (30 seconds if not set)
+}
+
+// New returns backend running tasks as the current user
+func New() *Backend {
+	return &Backend{}
+}
+
+// Start implements executor.Backend
+func (b *Backend) Start(opts executor.Options) executor.Result {
+	res := executor.Result{ExitCode: -1}
+	if err := checkSupported(opts); err != nil {
+		return b.fail(res, err)
+	}
+	if opts.DirFunc != nil {
+		opts.Dir = opts.DirFunc()
+	}
+
+	dir, err := os.MkdirTemp(b.TempDir, "executor-task-")
+	if err != nil {
+		return b.fail(res, err)
+	}
+	name, err := taskName()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return b.fail(res, err)
+	}
+	t := &task{name: name, dir: dir}
+	if err := t.writeScript(opts.Command, opts.Args, opts.Dir); err != nil {
+		t.cleanup()
+		return b.fail(res, err)
+	}
+	if err := b.register(t); err != nil {
+		t.cleanup()
+		return b.fail(res, err)
+	}
+	started := time.Now()
+	if err := schtasks("/Run", "/TN", name); err != nil {
+		_ = schtasks("/Delete", "/TN", name, "/F")
+		t.cleanup()
+		return b.fail(res, err)
+	}
+	res.StartOk = true
+
+	ctx, cancel := taskContext(opts)
+	if !opts.Wait {
+		// Task is deleted in background once it finished
+		go func() {
+			defer cancel()
+			if _, err := t.wait(ctx, b.pollInterval(), b.startTimeout()); err != nil {
+				_ = schtasks("/End", "/TN", name)
+			}
+			_ = schtasks("/Delete", "/TN", name, "/F")
+			t.cleanup()
+		}()
+		return res
+	}
+	defer cancel()
+	defer t.cleanup()
+	defer func() { _ = schtasks("/Delete", "/TN", name, "/F") }()
+
+	code, err := t.wait(ctx, b.pollInterval(), b.startTimeout())
+	res.Duration = time.Since(started)
+	if err != nil {
+		_ = schtasks("/End", "/TN", name)
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			res.EndReason = executor.EndTimeout
+		case ctx.Err() != nil:
+			res.EndReason = executor.EndCanceled
+		case errors.Is(err, errNotStarted):
+			res.StartOk = false
+			return b.fail(res, err)
+		default:
+			// Task ended without running the script to the end, like when it was ended from outside
+			res.EndReason = executor.EndSignaled
+			executor.Diagln(err)
+		}
+	} else {
+		res.EndReason = executor.EndExited
+		res.ExitCode = code
+		res.DoneOk = code == 0
+	}
+	b.deliverOutput(t, opts, &res)
+	return res
+}
+
+// fail reports <err> preventing start of task and returns <res> with it
+func (b *Backend) fail(res executor.Result, err error) executor.Result {
+	executor.Diagln(err)
+	res.Err = err
+	return res
+}
+
+// register registers task <t>
+func (b *Backend) register(t *task) error {
+	if b.User != "" && b.Password != "" && !b.Interactive {
+		return powershell(b.registerScript(t))
+	}
+	return schtasks(b.createArgs(t)...)
+}
+
+// registerScript returns PowerShell script registering task <t> to run as User with Password
+func (b *Backend) registerScript(t *task) string {
+	runLevel := "Limited"
+	if b.Highest {
+		runLevel = "Highest"
+	}
+	var sb strings.Builder
+	sb.WriteString("$ErrorActionPreference = 'Stop'\r\n")
+	fmt.Fprintf(&sb, "$action = New-ScheduledTaskAction -Execute %v\r\n", executor.QuotePowerShell(t.script()))
+	fmt.Fprintf(&sb, "Register-ScheduledTask -TaskName %v -Action $action -User %v -Password %v -RunLevel %v -Force | Out-Null\r\n",
+		executor.QuotePowerShell(t.name), executor.QuotePowerShell(b.User), executor.QuotePowerShell(b.Password), runLevel)
+	return sb.String()
+}
+
+// createArgs returns schtasks arguments registering task <t> without password
+func (b *Backend) createArgs(t *task) []string {
+	// Start time is required, task is only run on demand
+	args := []string{"/Create", "/F", "/TN", t.name, "/TR", `"` + t.script() + `"`, "/SC", "ONCE", "/ST", "00:00"}
+	if b.User != "" {
+		args = append(args, "/RU", b.User)
+	}
+	if b.Highest {
+		args = append(args, "/RL", "HIGHEST")
+	}
+	if b.Interactive {
+		args = append(args, "/IT")
+	}
+	return args
+}
+
+// pollInterval returns interval between checks whether task finished
+func (b *Backend) pollInterval() time.Duration {
+	if b.PollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return b.PollInterval
+}
+
+// startTimeout returns time allotted for task to start running
+func (b *Backend) startTimeout() time.Duration {
+	if b.StartTimeout <= 0 {
+		return defaultStartTimeout
+	}
+	return b.StartTimeout
+}
+
+// deliverOutput prints, captures and passes output of finished task <t> to callbacks according to <opts>
+func (b *Backend) deliverOutput(t *task, opts executor.Options, res *executor.Result) {
+	data, err := os.ReadFile(t.outputPath())
+	if err != nil {
+		return
+	}
+	output := string(data)
+	res.StdoutBytes = int64(len(data))
+	if opts.Print {
+		fmt.Print(output)
+	}
+	if opts.Capture {
+		res.Output = output
+	}
+	if opts.SeparateCapture {
+		res.Stdout = output
+	}
+	if opts.OnLine != nil {
+		for _, line := range strings.SplitAfter(output, "\n") {
+			if strings.HasSuffix(line, "\n") {
+				opts.OnLine(strings.TrimRight(line, "\r\n"), nil)
+			}
+		}
+	}
+}
+
+// task represents files of registered task
+type task struct {
+	name string
+	dir  string
+}
+
+// script returns path to batch file run by the task
+func (t *task) script() string {
+	return filepath.Join(t.dir, "run.cmd")
+}
+
+// outputPath returns path to file with output of the task
+func (t *task) outputPath() string {
+	return filepath.Join(t.dir, "output.txt")
+}
+
+// exitCodePath returns path to file the exit code is written into once the task finished
+func (t *task) exitCodePath() string {
+	return filepath.Join(t.dir, "exit_code.txt")
+}
+
+// writeScript writes batch file running <command> with <args> in <dir> (unchanged if empty), redirecting output
+// and writing exit code into files
+func (t *task) writeScript(command string, args []string, dir string) error {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{command}, args...) {
+		parts = append(parts, batchQuote(arg))
+	}
+	var sb strings.Builder
+	sb.WriteString("@echo off\r\n")
+	if dir != "" {
+		fmt.Fprintf(&sb, "cd /d %v\r\n", batchQuote(dir))
+	}
+	fmt.Fprintf(&sb, "%v > %v 2>&1\r\n", strings.Join(parts, " "), batchQuote(t.outputPath()))
+	// Written into temporary file and renamed, so it is never read half-written
+	tmp := t.exitCodePath() + ".tmp"
+	fmt.Fprintf(&sb, "echo %%errorlevel%% > %v\r\n", batchQuote(tmp))
+	fmt.Fprintf(&sb, "move /y %v %v > nul\r\n", batchQuote(tmp), batchQuote(t.exitCodePath()))
+	return os.WriteFile(t.script(), []byte(sb.String()), 0644)
+}
+
+// wait waits until the task wrote its exit code or <ctx> is done, checking every <interval>. Returns the exit code.
+// Returns error if the task did not start running in <startTimeout>, or ended without writing exit code
+func (t *task) wait(ctx context.Context, interval time.Duration, startTimeout time.Duration) (int, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	started := time.Now()
+	var queried time.Time
+	for {
+		if code, ok := t.exitCode(); ok {
+			return code, nil
+		}
+		if time.Since(queried) >= statusInterval {
+			queried = time.Now()
+			result, err := queryLastResult(t.name)
+			switch {
+			case err != nil:
+				// Status is checked again later, task may still finish
+			case result == resultHasNotRun || result == resultQueued:
+				if time.Since(started) >= startTimeout {
+					return -1, fmt.Errorf("%w in %v (last result 0x%X)", errNotStarted, startTimeout, result)
+				}
+			case result != resultRunning:
+				// Exit code is written before the task ends
+				if code, ok := t.exitCode(); ok {
+					return code, nil
+				}
+				return -1, fmt.Errorf("task ended without exit code (last result 0x%X)", result)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// exitCode returns exit code written by the task, if it finished
+func (t *task) exitCode() (int, bool) {
+	data, err := os.ReadFile(t.exitCodePath())
+	if err != nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return code, err == nil
+}
+
+// cleanup removes files of the task
+func (t *task) cleanup() {
+	_ = os.RemoveAll(t.dir)
+}
+
+// batchQuote returns <s> quoted to be used as a single argument in batch file.
+// Percent signs are doubled, as carets don't escape them in batch files
+func batchQuote(s string) string {
+	return strings.ReplaceAll(executor.QuoteCmdExe(s), "^%", "%%")
+}
+
+// taskName returns unique name of transient task
+func taskName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "executor-" + hex.EncodeToString(b), nil
+}
+
+// taskContext returns context ending task started with <opts>, by Options.Context and timeout
+func taskContext(opts executor.Options) (context.Context, context.CancelFunc) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := opts.TimeoutDuration
+	if timeout <= 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// schtasks runs schtasks.exe with <args>, returning error with its output if it failed
+func schtasks(args ...string) error {
+	out, err := exec.Command("schtasks", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks %v: %v: %v", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// queryLastResult returns Last Result of task <name>, which tells whether it is not started, running or finished
+func queryLastResult(name string) (uint32, error) {
+	out, err := exec.Command("schtasks", "/Query", "/TN", name, "/V", "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return 0, fmt.Errorf("schtasks /Query: %v", err)
+	}
+	r := csv.NewReader(strings.NewReader(string(out)))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, record := range records {
+		if len(record) < columnsCount {
+			continue
+		}
+		// Reported as signed or unsigned decimal, depending on Windows version
+		result, err := strconv.ParseInt(strings.TrimSpace(record[columnLastResult]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return uint32(result), nil
+	}
+	return 0, errors.New("schtasks /Query: task is not listed")
+}
+
+// powershell runs PowerShell <script> given on StdIn, returning error with its output if it failed
+func powershell(script string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "-")
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("powershell: %v: %v", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// checkSupported returns executor.ErrUnsupported naming the first option of <opts> which task can't honor
+func checkSupported(opts executor.Options) error {
+	var option string
+	switch {
+	case opts.NewConsole:
+		option = "NewConsole"
+	case opts.Hide:
+		option = "Hide"
+	case opts.Inherit:
+		option = "Inherit"
+	case opts.OnChar != nil:
+		option = "OnChar"
+	case len(opts.EnvFiles) > 0:
+		option = "EnvFiles"
+	case opts.EnvSpec != nil:
+		option = "EnvSpec"
+	case len(opts.Env) > 0:
+		option = "Env"
+	case opts.NoInheritEnv:
+		option = "NoInheritEnv"
+	case opts.CreateDir:
+		option = "CreateDir"
+	case opts.TempDir:
+		option = "TempDir"
+	case len(opts.CollectArtifacts) > 0:
+		option = "CollectArtifacts"
+	case opts.MemoryLimit > 0:
+		option = "MemoryLimit"
+	case opts.CPUTimeLimit > 0:
+		option = "CPUTimeLimit"
+	case opts.IOPriority.Class != executor.IOClassNone:
+		option = "IOPriority"
+	case opts.NoNetwork:
+		option = "NoNetwork"
+	case opts.Sandbox != nil:
+		option = "Sandbox"
+	case opts.RestrictToken:
+		option = "RestrictToken"
+	case opts.UserSession:
+		option = "UserSession"
+	case opts.OnStats != nil:
+		option = "OnStats"
+	case opts.ExpandGlobs:
+		option = "ExpandGlobs"
+	case opts.StdinFunc != nil:
+		option = "StdinFunc"
+	case opts.StdinSource != executor.StdinInherit:
+		option = "StdinSource"
+	case len(opts.AutoAnswer) > 0:
+		option = "AutoAnswer"
+	case opts.ExpectedSHA256 != "":
+		option = "ExpectedSHA256"
+	case opts.Verifier != nil:
+		option = "Verifier"
+	case opts.RequireSignature:
+		option = "RequireSignature"
+	case opts.MuxOutput != nil:
+		option = "MuxOutput"
+	case opts.Encoding != 0:
+		option = "Encoding"
+	case len(opts.Transforms) > 0:
+		option = "Transforms"
+	case opts.ForwardSignals:
+		option = "ForwardSignals"
+	case opts.DrainTimeout > 0:
+		option = "DrainTimeout"
+	case opts.ReadyWhen != nil:
+		option = "ReadyWhen"
+	case opts.ReadyAddress != "":
+		option = "ReadyAddress"
+	case opts.SampleHead > 0 || opts.SampleTail > 0:
+		option = "SampleHead"
+	case len(opts.SeverityRules) > 0 || opts.OnSeverityLine != nil:
+		option = "SeverityRules"
+	case len(opts.FailIfOutputMatches) > 0:
+		option = "FailIfOutputMatches"
+	case opts.RequireOutputMatch != nil:
+		option = "RequireOutputMatch"
+	case opts.IgnoreBrokenPipe:
+		option = "IgnoreBrokenPipe"
+	case opts.Cleanup:
+		option = "Cleanup"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: Options.%v with task scheduler backend", executor.ErrUnsupported, option)
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScriptOptions represents options to run a multi-line script
+type ScriptOptions struct {
+	Shell string   // Interpreter to run the script with (cmd on Windows and /bin/sh elsewhere if not set)
+	Env   []string // Additional environment variables in "KEY=VALUE" form
+	Dir   string   // Working directory
+}
+
+// RunScript writes <script> to a temporary file readable only by the current user, runs it with the interpreter
+// set by ScriptOptions.Shell until it exits or <ctx> is done and removes the file.
+//
+// If ScriptOptions.Shell is not set and <script> starts with "#!", the shebang interpreter is used. On Windows
+// known interpreters (python, bash of Git for Windows or WSL, powershell, pwsh, cmd) are mapped to native ones
+// and other ones are looked up in PATH by name.
+//
+// Output is captured into Result.Output, Result.Stdout and Result.Stderr.
+// Returns Result.Err if script did not finish successfully, like ExitError with StdErr tail
+func RunScript(ctx context.Context, script string, sopts ScriptOptions) (Result, error) {
+	tmp, err := os.MkdirTemp("", "executor-script-")
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	shell := sopts.Shell
+	if shell == "" {
+		shell = defaultShell
+	}
+	name := scriptName(shell)
+	var interpreter string
+	var interpreterArgs []string
+	hasShebang := false
+	if sopts.Shell == "" {
+		interpreter, interpreterArgs, hasShebang = parseShebang(script)
+		if hasShebang {
+			name = scriptName(interpreter)
+		}
+	}
+	if name == "script.cmd" {
+		// Labels and multi-line blocks break on LF line endings
+		script = strings.ReplaceAll(strings.ReplaceAll(script, "\r\n", "\n"), "\n", "\r\n")
+	}
+	path := filepath.Join(tmp, name)
+	if err := os.WriteFile(path, []byte(script), 0700); err != nil {
+		return Result{ExitCode: -1}, err
+	}
+
+	command, args := shell, scriptArgs(shell, path)
+	if hasShebang {
+		command, args, err = shebangCommand(interpreter, interpreterArgs, path)
+		if err != nil {
+			return Result{ExitCode: -1}, err
+		}
+	}
+	opts := Options{
+		Command:         command,
+		Args:            args,
+		Dir:             sopts.Dir,
+		Wait:            true,
+		Capture:         true,
+		SeparateCapture: true,
+		Context:         ctx,
+		Env:             sopts.Env,
+	}
+
+	res := Start(opts)
+	return res, res.Err
+}
+
+// parseShebang returns interpreter and its arguments from "#!" line of <script>.
+// "/usr/bin/env" is skipped, so the actual interpreter is returned
+func parseShebang(script string) (string, []string, bool) {
+	if !strings.HasPrefix(script, "#!") {
+		return "", nil, false
+	}
+	line := strings.SplitN(script[2:], "\n", 2)[0]
+	fields := strings.Fields(strings.TrimSuffix(line, "\r"))
+	if len(fields) > 0 && scriptKind(fields[0]) == "env" {
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// scriptKind returns lowercase name of <shell> executable without directory and extension
+func scriptKind(shell string) string {
+	base := strings.ToLower(filepath.Base(strings.ReplaceAll(shell, "\\", "/")))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// scriptName returns name of script file with extension <shell> requires
+func scriptName(shell string) string {
+	switch scriptKind(shell) {
+	case "cmd":
+		return "script.cmd"
+	case "powershell", "pwsh":
+		return "script.ps1"
+	case "python", "python3":
+		return "script.py"
+	default:
+		return "script"
+	}
+}
+
+// scriptArgs returns arguments for <shell> to run script at <path>
+func scriptArgs(shell string, path string) []string {
+	switch scriptKind(shell) {
+	case "cmd":
+		return []string{"/d", "/c", path}
+	case "powershell", "pwsh":
+		return []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", path}
+	default:
+		return []string{path}
+	}
+}
@@ -0,0 +1,78 @@
+// +build windows
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultShell is the interpreter of scripts if ScriptOptions.Shell is not set
+const defaultShell = "cmd"
+
+// shebangCommand returns command to run script at <path> with shebang <interpreter> and its <args>,
+// mapped to a native Windows interpreter
+func shebangCommand(interpreter string, args []string, path string) (string, []string, error) {
+	switch kind := scriptKind(interpreter); kind {
+	case "python", "python2", "python3":
+		// Store stub "python" may be found instead of real one, "py" launcher selects installed version
+		for _, name := range []string{"py", "python3", "python"} {
+			if found, err := exec.LookPath(name); err == nil {
+				if name == "py" && kind != "python" {
+					args = append([]string{"-" + strings.TrimPrefix(kind, "python")}, args...)
+				}
+				return found, append(args, path), nil
+			}
+		}
+		return "", nil, fmt.Errorf("python interpreter for shebang %q not found", interpreter)
+	case "bash", "sh":
+		for _, env := range []string{"ProgramFiles", "ProgramW6432", "LocalAppData"} {
+			root := os.Getenv(env)
+			if root == "" {
+				continue
+			}
+			gitBash := filepath.Join(root, "Git", "bin", "bash.exe")
+			if env == "LocalAppData" {
+				gitBash = filepath.Join(root, "Programs", "Git", "bin", "bash.exe")
+			}
+			if _, err := os.Stat(gitBash); err == nil {
+				return gitBash, append(args, filepath.ToSlash(path)), nil
+			}
+		}
+		found, err := exec.LookPath("bash")
+		if err != nil {
+			return "", nil, fmt.Errorf("bash for shebang %q not found, install Git for Windows or WSL", interpreter)
+		}
+		if strings.EqualFold(filepath.Dir(found), filepath.Join(os.Getenv("SystemRoot"), "System32")) {
+			// WSL bash sees Windows drives under /mnt
+			return found, append(args, wslPath(path)), nil
+		}
+		return found, append(args, filepath.ToSlash(path)), nil
+	case "powershell", "pwsh":
+		shell := "powershell"
+		if _, err := exec.LookPath("pwsh"); err == nil && kind == "pwsh" {
+			shell = "pwsh"
+		}
+		return shell, append(args, scriptArgs(shell, path)...), nil
+	case "cmd":
+		return "cmd", scriptArgs("cmd", path), nil
+	default:
+		found, err := exec.LookPath(kind)
+		if err != nil {
+			return "", nil, fmt.Errorf("interpreter for shebang %q not found: %w", interpreter, err)
+		}
+		return found, append(args, path), nil
+	}
+}
+
+// wslPath returns Windows <path> as seen from WSL, like "C:\Temp\x" -> "/mnt/c/Temp/x"
+func wslPath(path string) string {
+	vol := filepath.VolumeName(path)
+	if len(vol) != 2 || vol[1] != ':' {
+		return filepath.ToSlash(path)
+	}
+	return "/mnt/" + strings.ToLower(vol[:1]) + filepath.ToSlash(path[2:])
+}
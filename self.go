@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// procSelfExe is a Linux procfs link to the executable of the current process, usable even if it was deleted
+const procSelfExe = "/proc/self/exe"
+
+// SelfOptions represents options to re-launch the current executable
+type SelfOptions struct {
+	Env        []string   // Additional environment variables in "KEY=VALUE" form
+	Dir        string     // Working directory
+	Elevated   bool       // Launch with administrator (root) privileges, using UAC prompt on Windows and sudo elsewhere?
+	Detached   bool       // Detach from the current console and session, so process outlives the current one?
+	ExtraFiles []*os.File // Open files to pass to the process as file descriptors 3, 4, ... (not supported on Windows)
+}
+
+// SelfPath returns path to the executable of the current process.
+//
+// If the executable was deleted or moved after start, procfs link to it is returned on Linux
+func SelfPath() (string, error) {
+	exe, err := os.Executable()
+	if err == nil {
+		exe = strings.TrimSuffix(exe, " (deleted)")
+		if _, statErr := os.Stat(exe); statErr == nil {
+			return exe, nil
+		}
+	}
+	if _, statErr := os.Stat(procSelfExe); statErr == nil {
+		return procSelfExe, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("executable %v does not exist anymore", exe)
+}
@@ -0,0 +1,49 @@
+// +build !windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Self launches the current executable again with <args> according to <opts>. Standard streams are inherited,
+// unless detached. The executable itself, not sudo used for elevation, is checked against the global policy,
+// and the launch is audited
+func Self(args []string, opts SelfOptions) (*os.Process, error) {
+	path, err := SelfPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExecution(Options{Command: path, Args: args}, opts.Dir); err != nil {
+		return nil, err
+	}
+
+	name, argv := path, args
+	if opts.Elevated && os.Geteuid() != 0 {
+		// Link in procfs would point to sudo itself
+		if path == procSelfExe {
+			return nil, errors.New("can not elevate deleted executable")
+		}
+		name, argv = "sudo", append([]string{"-E", "--", path}, args...)
+	}
+
+	cmd := exec.Command(name, argv...)
+	cmd.Dir = opts.Dir
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.ExtraFiles = opts.ExtraFiles
+	if opts.Detached {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	} else {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if _, err := startAudited(cmd, nil, cmd.Start); err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
@@ -0,0 +1,68 @@
+// +build windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Self launches the current executable again with <args> according to <opts>. Standard streams are inherited,
+// unless detached. Launches are checked against the global policy and audited, elevated ones included.
+//
+// Elevated process is launched with ShellExecute and UAC prompt, in which case nil process is returned on success,
+// as no handle to it is available. Elevated process does not receive SelfOptions.Env
+func Self(args []string, opts SelfOptions) (*os.Process, error) {
+	if len(opts.ExtraFiles) > 0 {
+		return nil, errors.New("passing extra files is not supported on Windows")
+	}
+	path, err := SelfPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExecution(Options{Command: path, Args: args}, opts.Dir); err != nil {
+		return nil, err
+	}
+
+	if opts.Elevated {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = syscall.EscapeArg(arg)
+		}
+		verb, _ := windows.UTF16PtrFromString("runas")
+		file, _ := windows.UTF16PtrFromString(path)
+		params, _ := windows.UTF16PtrFromString(strings.Join(quoted, " "))
+		var dir *uint16
+		if opts.Dir != "" {
+			dir, _ = windows.UTF16PtrFromString(opts.Dir)
+		}
+		cmd := &exec.Cmd{Path: path, Args: append([]string{path}, args...), Dir: opts.Dir}
+		_, err := startAudited(cmd, nil, func() error {
+			return windows.ShellExecute(0, verb, file, params, dir, windows.SW_NORMAL)
+		})
+		return nil, err
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = append(os.Environ(), opts.Env...)
+	if opts.Detached {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			CreationFlags: windows.DETACHED_PROCESS | windows.CREATE_NEW_PROCESS_GROUP,
+		}
+	} else {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if _, err := startAudited(cmd, nil, cmd.Start); err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// serviceHostArg is a command line argument which makes RunServiceHost run the process as a service
+const serviceHostArg = "--executor-service-host"
+
+// ServiceConfig represents a command to run as a Windows service
+type ServiceConfig struct {
+	Name        string   // Service name
+	DisplayName string   // Service display name
+	Description string   // Service description
+	AutoStart   bool     // Start service automatically on system boot?
+	Command     string   // Command to run
+	Args        []string // Command arguments
+	Dir         string   // Working directory
+	StdoutFile  string   // File to append StdOut of process to
+	StderrFile  string   // File to append StdErr of process to
+	EventLog    bool     // Write lines of StdOut and StdErr into Windows Event Log (as Info and Error events)?
+}
+
+// encode returns <cfg> as a single command line argument
+func (cfg ServiceConfig) encode() (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decodeServiceConfig parses ServiceConfig encoded by ServiceConfig.encode
+func decodeServiceConfig(arg string) (ServiceConfig, error) {
+	var cfg ServiceConfig
+	data, err := base64.StdEncoding.DecodeString(arg)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
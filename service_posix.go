@@ -0,0 +1,36 @@
+// +build !windows
+
+package executor
+
+import (
+	"errors"
+)
+
+// errNoServices is returned by service helpers on platforms other than Windows
+var errNoServices = errors.New("services are only supported on Windows")
+
+// InstallService registers a Windows service running command described by <cfg>
+func InstallService(cfg ServiceConfig) error {
+	return errNoServices
+}
+
+// RemoveService deletes Windows service with <name>
+func RemoveService(name string) error {
+	return errNoServices
+}
+
+// StartService starts Windows service with <name>
+func StartService(name string) error {
+	return errNoServices
+}
+
+// StopService stops Windows service with <name>
+func StopService(name string) error {
+	return errNoServices
+}
+
+// RunServiceHost runs the service if the program was launched by the service control manager.
+// Always returns false on this platform
+func RunServiceHost() (bool, error) {
+	return false, nil
+}
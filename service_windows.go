@@ -0,0 +1,278 @@
+// +build windows
+
+package executor
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// InstallService registers a Windows service running command described by <cfg>.
+//
+// Service binary is the current executable, which must call RunServiceHost at the beginning of main()
+func InstallService(cfg ServiceConfig) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	encoded, err := cfg.encode()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	startType := uint32(mgr.StartManual)
+	if cfg.AutoStart {
+		startType = mgr.StartAutomatic
+	}
+	s, err := m.CreateService(cfg.Name, exe, mgr.Config{
+		DisplayName: cfg.DisplayName,
+		Description: cfg.Description,
+		StartType:   startType,
+	}, serviceHostArg, encoded)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if cfg.EventLog {
+		err = eventlog.InstallAsEventCreate(cfg.Name, eventlog.Error|eventlog.Warning|eventlog.Info)
+		if err != nil {
+			_ = s.Delete()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveService deletes Windows service with <name>
+func RemoveService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	// Event source may not be installed
+	_ = eventlog.Remove(name)
+	return nil
+}
+
+// StartService starts Windows service with <name>
+func StartService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.Start()
+}
+
+// StopService stops Windows service with <name> and waits up to 30 seconds for it to stop
+func StopService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for status.State != svc.Stopped && time.Now().Before(deadline) {
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunServiceHost runs the service if the program was launched by the service control manager
+// for a service installed with InstallService. Returns true if it did, in which case the program should exit
+func RunServiceHost() (bool, error) {
+	if len(os.Args) < 3 || os.Args[1] != serviceHostArg {
+		return false, nil
+	}
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+
+	cfg, err := decodeServiceConfig(os.Args[2])
+	if err != nil {
+		return true, err
+	}
+	return true, svc.Run(cfg.Name, &serviceHandler{cfg: cfg})
+}
+
+// serviceHandler runs command of service and stops it on request of service control manager
+type serviceHandler struct {
+	cfg ServiceConfig
+}
+
+// Execute implements svc.Handler
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	if err := checkExecution(Options{Command: h.cfg.Command, Args: h.cfg.Args}, h.cfg.Dir); err != nil {
+		return true, 2
+	}
+	cmd := exec.Command(h.cfg.Command, h.cfg.Args...)
+	cmd.Dir = h.cfg.Dir
+
+	closers, err := h.redirect(cmd)
+	defer func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i].Close()
+		}
+	}()
+	if err != nil {
+		return true, 1
+	}
+
+	res, err := startAudited(cmd, nil, cmd.Start)
+	if err != nil {
+		return true, 2
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		audit(AuditFinish, res, cmd.ProcessState)
+		done <- err
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-done:
+			status <- svc.Status{State: svc.StopPending}
+			if err != nil {
+				return true, uint32(cmd.ProcessState.ExitCode())
+			}
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				killWithDescendants(cmd.Process)
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
+
+// redirect connects StdOut and StdErr of <cmd> to files and Event Log according to the service config.
+// Returns resources to close in reverse order after process finished
+func (h *serviceHandler) redirect(cmd *exec.Cmd) ([]io.Closer, error) {
+	var closers []io.Closer
+	var stdout, stderr []io.Writer
+
+	if h.cfg.StdoutFile != "" {
+		file, err := os.OpenFile(h.cfg.StdoutFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return closers, err
+		}
+		closers = append(closers, file)
+		stdout = append(stdout, file)
+	}
+	if h.cfg.StderrFile != "" {
+		file, err := os.OpenFile(h.cfg.StderrFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return closers, err
+		}
+		closers = append(closers, file)
+		stderr = append(stderr, file)
+	}
+	if h.cfg.EventLog {
+		log, err := eventlog.Open(h.cfg.Name)
+		if err != nil {
+			return closers, err
+		}
+		closers = append(closers, log)
+		outWriter, errWriter := newEventLogWriter(log.Info), newEventLogWriter(log.Error)
+		closers = append(closers, outWriter, errWriter)
+		stdout = append(stdout, outWriter)
+		stderr = append(stderr, errWriter)
+	}
+
+	cmd.Stdout = io.MultiWriter(stdout...)
+	cmd.Stderr = io.MultiWriter(stderr...)
+	return closers, nil
+}
+
+// eventLogWriter writes each line written to it as a separate Event Log event
+type eventLogWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+}
+
+// newEventLogWriter returns writer reporting lines with <report>
+func newEventLogWriter(report func(eid uint32, msg string) error) *eventLogWriter {
+	pr, pw := io.Pipe()
+	w := &eventLogWriter{pw: pw, done: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			_ = report(1, scanner.Text())
+		}
+		// Keep writes from blocking after scan error
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	return w
+}
+
+// Write implements io.Writer
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close reports the remaining line and stops the writer
+func (w *eventLogWriter) Close() error {
+	err := w.pw.Close()
+	<-w.done
+	return err
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Severity represents level of output line
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // Line matching no rule
+	SeverityWarning                 // Warning
+	SeverityError                   // Error
+)
+
+// String returns name of the level
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// SeverityRule represents rule giving <Severity> to lines matching <Pattern>
+type SeverityRule struct {
+	Pattern  *regexp.Regexp // Pattern of line
+	Severity Severity       // Level of matching line
+}
+
+// DefaultSeverityRules returns rules recognizing common error and warning lines of compilers and loggers,
+// like "error: ...", "ERROR ..." and "Warning: ..."
+func DefaultSeverityRules() []SeverityRule {
+	return []SeverityRule{
+		{Pattern: regexp.MustCompile(`(?i)\b(error|fatal|panic)\b`), Severity: SeverityError},
+		{Pattern: regexp.MustCompile(`(?i)\b(warning|warn)\b`), Severity: SeverityWarning},
+	}
+}
+
+// lineClassifier gives levels to lines by rules and counts lines of each level
+type lineClassifier struct {
+	mu     sync.Mutex
+	rules  []SeverityRule
+	counts [SeverityError + 1]int
+}
+
+// newLineClassifier returns classifier of lines by <rules>, the first matching rule wins
+func newLineClassifier(rules []SeverityRule) *lineClassifier {
+	return &lineClassifier{rules: rules}
+}
+
+// classify returns level of line <l> and counts it
+func (c *lineClassifier) classify(l string) Severity {
+	sev := SeverityInfo
+	for _, rule := range c.rules {
+		if rule.Pattern.MatchString(l) {
+			sev = rule.Severity
+			break
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sev >= SeverityInfo && sev <= SeverityError {
+		c.counts[sev]++
+	}
+	return sev
+}
+
+// wrapOnLine returns line callback classifying lines and passing them to <onSeverityLine> (optional), then to
+// <onLine> (optional)
+func (c *lineClassifier) wrapOnLine(onSeverityLine func(l string, sev Severity, p *os.Process),
+	onLine func(l string, p *os.Process)) func(l string, p *os.Process) {
+	return func(l string, p *os.Process) {
+		sev := c.classify(l)
+		if onSeverityLine != nil {
+			onSeverityLine(l, sev, p)
+		}
+		if onLine != nil {
+			onLine(l, p)
+		}
+	}
+}
+
+// fill sets line counts of <res>. Safe to call on nil classifier
+func (c *lineClassifier) fill(res *Result) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res.WarningLines = c.counts[SeverityWarning]
+	res.ErrorLines = c.counts[SeverityError]
+}
@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellMetaChars are characters having special meaning to POSIX shells, cmd.exe or PowerShell
+const shellMetaChars = "|&;<>()$`\\\"'*?[]{}#~=%!^,\n\r\t "
+
+// ContainsShellMeta returns true if <s> contains characters interpreted by shells
+func ContainsShellMeta(s string) bool {
+	return strings.ContainsAny(s, shellMetaChars)
+}
+
+// CheckShellSafe returns error wrapping ErrShellMeta if any of <values> contains characters interpreted by shells.
+// Use it to refuse untrusted values before embedding them into a shell command string
+func CheckShellSafe(values ...string) error {
+	for _, value := range values {
+		if idx := strings.IndexAny(value, shellMetaChars); idx >= 0 {
+			return fmt.Errorf("%w: %q in %q", ErrShellMeta, value[idx], value)
+		}
+	}
+	return nil
+}
+
+// ArgsFromUntrusted returns <values> to use as Options.Args: each value is passed to the process
+// as a separate argv entry and is never interpreted by a shell, as Start runs commands directly.
+// Returns error if a value contains NUL character, which can not be passed in argv
+func ArgsFromUntrusted(values ...string) ([]string, error) {
+	args := make([]string, len(values))
+	for i, value := range values {
+		if strings.IndexByte(value, 0) >= 0 {
+			return nil, fmt.Errorf("argument %v contains NUL character", i)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
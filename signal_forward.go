@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// signalForwarder relays signals received by this process to the processes started with Options.ForwardSignals.
+// Handler is installed once the first process is added and removed after the last one is done,
+// restoring default handling of the signals
+type signalForwarder struct {
+	mu    sync.Mutex
+	procs map[*os.Process]struct{}
+	ch    chan os.Signal
+	done  chan struct{}
+}
+
+// sharedSignalForwarder is the handler shared by all running processes
+var sharedSignalForwarder = &signalForwarder{procs: map[*os.Process]struct{}{}}
+
+// add starts relaying signals to <proc>, installing the handler if it is the first process.
+// Returns function to call once the process is done
+func (f *signalForwarder) add(proc *os.Process) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.procs) == 0 {
+		f.ch = make(chan os.Signal, 1)
+		f.done = make(chan struct{})
+		signal.Notify(f.ch, forwardedSignals...)
+		ch, done := f.ch, f.done
+		goTracked(func() { f.relay(ch, done) })
+	}
+	f.procs[proc] = struct{}{}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { f.remove(proc) })
+	}
+}
+
+// remove stops relaying signals to <proc>, removing the handler if it was the last process
+func (f *signalForwarder) remove(proc *os.Process) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.procs, proc)
+	if len(f.procs) == 0 {
+		signal.Stop(f.ch)
+		close(f.done)
+	}
+}
+
+// relay forwards signals from <ch> to the processes until <done> is closed
+func (f *signalForwarder) relay(ch chan os.Signal, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-ch:
+			f.mu.Lock()
+			for proc := range f.procs {
+				if err := forwardSignal(proc, sig); err != nil {
+					diagln(err)
+				}
+			}
+			f.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// +build !windows
+
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardedSignals are the signals relayed to processes started with Options.ForwardSignals
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// forwardSignal sends <sig> to <proc>. SIGINT and SIGQUIT are not sent to process in the same process group
+// as this one, as keyboard generated signals are delivered by terminal to the whole group, so it got them already
+func forwardSignal(proc *os.Process, sig os.Signal) error {
+	if sig == os.Interrupt || sig == syscall.SIGQUIT {
+		if pgid, err := syscall.Getpgid(proc.Pid); err == nil && pgid == syscall.Getpgrp() {
+			return nil
+		}
+	}
+	if err := proc.Signal(sig); err != nil && err != os.ErrProcessDone {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+)
+
+// forwardedSignals are the signals relayed to processes started with Options.ForwardSignals
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// forwardSignal does nothing, as the process sharing the console receives Ctrl+C itself.
+// Handling the signal only keeps this process running until the started one exits
+func forwardSignal(_ *os.Process, _ os.Signal) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+// +build darwin
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// verifySignature checks code signature of executable at <path> with codesign and Gatekeeper assessment with spctl.
+// If <subject> is not empty, the leaf signing authority must contain it
+func verifySignature(path string, subject string) error {
+	if out, err := exec.Command("codesign", "--verify", "--strict", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrUnsignedBinary, path, strings.TrimSpace(string(out)))
+	}
+	if out, err := exec.Command("spctl", "--assess", "--type", "execute", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %v rejected by Gatekeeper: %v", ErrUnsignedBinary, path, strings.TrimSpace(string(out)))
+	}
+
+	if subject == "" {
+		return nil
+	}
+	// Signing information is printed to StdErr, the first authority is the signer, the rest is the chain
+	out, _ := exec.Command("codesign", "-dv", "--verbose=2", path).CombinedOutput()
+	signer := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Authority=") {
+			signer = strings.TrimPrefix(line, "Authority=")
+			break
+		}
+	}
+	if !strings.Contains(signer, subject) {
+		return fmt.Errorf("%w: %v is signed by %q, expected %q", ErrUnsignedBinary, path, signer, subject)
+	}
+	return nil
+}
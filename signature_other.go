@@ -0,0 +1,12 @@
+// +build !windows,!darwin
+
+package executor
+
+import (
+	"errors"
+)
+
+// verifySignature returns error as executables have no standard code signatures on this platform
+func verifySignature(path string, subject string) error {
+	return errors.New("code signature verification is only supported on Windows and macOS")
+}
@@ -0,0 +1,119 @@
+// +build windows
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// cmsgSignerInfoParam is CryptMsgGetParam parameter type CMSG_SIGNER_INFO_PARAM
+const cmsgSignerInfoParam = 6
+
+var (
+	modcrypt32           = windows.NewLazySystemDLL("crypt32.dll")
+	procCryptMsgGetParam = modcrypt32.NewProc("CryptMsgGetParam")
+	procCryptMsgClose    = modcrypt32.NewProc("CryptMsgClose")
+)
+
+// cmsgSignerInfo represents leading fields of CMSG_SIGNER_INFO structure
+type cmsgSignerInfo struct {
+	Version      uint32
+	Issuer       windows.CertNameBlob
+	SerialNumber windows.CryptIntegerBlob
+}
+
+// verifySignature checks Authenticode signature of executable at <path> with WinVerifyTrust.
+// If <subject> is not empty, the name of the signer certificate must contain it
+func verifySignature(path string, subject string) error {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	file := windows.WinTrustFileInfo{
+		Size:     uint32(unsafe.Sizeof(windows.WinTrustFileInfo{})),
+		FilePath: pathPtr,
+	}
+	data := windows.WinTrustData{
+		Size:                            uint32(unsafe.Sizeof(windows.WinTrustData{})),
+		UIChoice:                        windows.WTD_UI_NONE,
+		RevocationChecks:                windows.WTD_REVOKE_NONE,
+		UnionChoice:                     windows.WTD_CHOICE_FILE,
+		StateAction:                     windows.WTD_STATEACTION_VERIFY,
+		FileOrCatalogOrBlobOrSgnrOrCert: unsafe.Pointer(&file),
+	}
+	err = windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, &data)
+	data.StateAction = windows.WTD_STATEACTION_CLOSE
+	_ = windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, &data)
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrUnsignedBinary, path, err)
+	}
+
+	if subject == "" {
+		return nil
+	}
+	signer, err := signerName(pathPtr)
+	if err != nil {
+		return fmt.Errorf("%w: can not get signer of %v: %v", ErrUnsignedBinary, path, err)
+	}
+	if !strings.Contains(signer, subject) {
+		return fmt.Errorf("%w: %v is signed by %q, expected %q", ErrUnsignedBinary, path, signer, subject)
+	}
+	return nil
+}
+
+// signerName returns simple display name of certificate which signed file at <path>
+func signerName(path *uint16) (string, error) {
+	var encoding, contentType, formatType uint32
+	var store, msg windows.Handle
+	err := windows.CryptQueryObject(
+		windows.CERT_QUERY_OBJECT_FILE,
+		unsafe.Pointer(path),
+		windows.CERT_QUERY_CONTENT_FLAG_PKCS7_SIGNED_EMBED,
+		windows.CERT_QUERY_FORMAT_FLAG_BINARY,
+		0,
+		&encoding, &contentType, &formatType,
+		&store, &msg, nil,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CertCloseStore(store, 0)
+	defer procCryptMsgClose.Call(uintptr(msg))
+
+	var size uint32
+	if r, _, err := procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerInfoParam, 0, 0, uintptr(unsafe.Pointer(&size))); r == 0 {
+		return "", err
+	}
+	buf := make([]byte, size)
+	if r, _, err := procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerInfoParam, 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); r == 0 {
+		return "", err
+	}
+	signerInfo := (*cmsgSignerInfo)(unsafe.Pointer(&buf[0]))
+
+	// Find signer certificate by issuer and serial number, other certificates in the store belong to the chain
+	certInfo := windows.CertInfo{Issuer: signerInfo.Issuer, SerialNumber: signerInfo.SerialNumber}
+	cert, err := windows.CertFindCertificateInStore(
+		store,
+		windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING,
+		0,
+		windows.CERT_FIND_SUBJECT_CERT,
+		unsafe.Pointer(&certInfo),
+		nil,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CertFreeCertificateContext(cert)
+
+	name := make([]uint16, 512)
+	n := windows.CertGetNameString(cert, windows.CERT_NAME_SIMPLE_DISPLAY_TYPE, 0, nil, &name[0], uint32(len(name)))
+	if n <= 1 {
+		return "", fmt.Errorf("signer certificate has no name")
+	}
+	return windows.UTF16ToString(name[:n]), nil
+}
@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// spillBuffer keeps the first <limit> bytes of captured output in memory and writes the rest into a temporary file
+type spillBuffer struct {
+	limit int64
+	mem   strings.Builder
+	file  *os.File
+	err   error
+}
+
+// Write appends <p> to memory or to the file, once memory limit is reached
+func (b *spillBuffer) Write(p []byte) {
+	if b.limit <= 0 || (b.file == nil && int64(b.mem.Len())+int64(len(p)) <= b.limit) {
+		b.mem.Write(p)
+		return
+	}
+	if b.err != nil {
+		return
+	}
+	if b.file == nil {
+		b.file, b.err = os.CreateTemp("", "executor-output-")
+		if b.err != nil {
+			diagln(b.err)
+			return
+		}
+	}
+	if _, b.err = b.file.Write(p); b.err != nil {
+		diagln(b.err)
+	}
+}
+
+// path returns path of the file with output beyond memory limit, or empty string if there is none
+func (b *spillBuffer) path() string {
+	if b.file == nil {
+		return ""
+	}
+	return b.file.Name()
+}
+
+// close closes the file, if any
+func (b *spillBuffer) close() {
+	if b.file != nil {
+		b.file.Close()
+	}
+}
+
+// OutputReader returns reader of the full captured output: Result.Output followed by content of Result.OutputFile
+func (r Result) OutputReader() (io.ReadCloser, error) {
+	return spilledReader(r.Output, r.OutputFile)
+}
+
+// StdoutReader returns reader of the full captured StdOut: Result.Stdout followed by content of Result.StdoutFile
+func (r Result) StdoutReader() (io.ReadCloser, error) {
+	return spilledReader(r.Stdout, r.StdoutFile)
+}
+
+// StderrReader returns reader of the full captured StdErr: Result.Stderr followed by content of Result.StderrFile
+func (r Result) StderrReader() (io.ReadCloser, error) {
+	return spilledReader(r.Stderr, r.StderrFile)
+}
+
+// spilledReader returns reader of <mem> followed by content of file at <path>, if it is not empty
+func spilledReader(mem string, path string) (io.ReadCloser, error) {
+	if path == "" {
+		return io.NopCloser(strings.NewReader(mem)), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(strings.NewReader(mem), file), file}, nil
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"os"
+	"time"
+)
+
+// defaultStatsInterval is an interval between resource usage samples if Options.StatsInterval is not set
+const defaultStatsInterval = time.Second
+
+// Stats represents resource usage of process tree
+type Stats struct {
+	Processes  int     // Number of processes in the tree
+	CPUPercent float64 // CPU usage since previous sample, 100 per fully used core
+	RSS        uint64  // Total resident memory size in bytes
+	ReadBytes  uint64  // Bytes read from storage since previous sample
+	WriteBytes uint64  // Bytes written to storage since previous sample
+}
+
+// processUsage represents cumulative resource usage of a single process
+type processUsage struct {
+	cpu        time.Duration
+	readBytes  uint64
+	writeBytes uint64
+}
+
+// statsSampler periodically reports resource usage of process tree
+type statsSampler struct {
+	done   chan struct{}
+	exited chan struct{}
+}
+
+// startStatsSampler starts calling <onStats> every <interval> with resource usage of process <p> and its descendants.
+// Sampling stops when the sampler is stopped or the process is not running anymore
+func startStatsSampler(p *os.Process, interval time.Duration, onStats func(s Stats)) *statsSampler {
+	if interval <= 0 {
+		interval = defaultStatsInterval
+	}
+	s := &statsSampler{done: make(chan struct{}), exited: make(chan struct{})}
+
+	goTracked(func() {
+		defer close(s.exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		prev := map[int]processUsage{}
+		prevTime := time.Now()
+		for {
+			select {
+			case <-s.done:
+				return
+			case now := <-ticker.C:
+				rootUsage, err := getProcessUsage(p.Pid)
+				if err != nil {
+					return
+				}
+				stats, cur := sampleProcessTree(p.Pid, rootUsage, prev)
+				stats.CPUPercent = float64(stats.cpu) / float64(now.Sub(prevTime)) * 100
+				prev, prevTime = cur, now
+				onStats(stats.Stats)
+			}
+		}
+	})
+
+	return s
+}
+
+// stop stops sampling and waits for the callback in progress to return. Safe to call on nil sampler
+func (s *statsSampler) stop() {
+	if s != nil {
+		close(s.done)
+		<-s.exited
+	}
+}
+
+// treeSample represents Stats with CPU time used since previous sample
+type treeSample struct {
+	Stats
+	cpu time.Duration
+}
+
+// sampleProcessTree sums usage of process with <pid> and its descendants since <prev> usage by PID.
+// Returns current usage by PID
+func sampleProcessTree(pid int, rootUsage processUsage, prev map[int]processUsage) (treeSample, map[int]processUsage) {
+	cur := map[int]processUsage{pid: rootUsage}
+	rootRSS, _ := processRSS(pid)
+	sample := treeSample{Stats: Stats{Processes: 1, RSS: rootRSS}}
+
+	children, _ := Children(pid)
+	for _, child := range flattenProcessTree(children) {
+		usage, err := getProcessUsage(child.PID)
+		if err != nil {
+			continue
+		}
+		cur[child.PID] = usage
+		sample.Processes++
+		sample.RSS += child.Memory
+	}
+
+	for pid, usage := range cur {
+		before := prev[pid]
+		// PID has been reused by another process
+		if usage.cpu < before.cpu || usage.readBytes < before.readBytes || usage.writeBytes < before.writeBytes {
+			before = processUsage{}
+		}
+		sample.cpu += usage.cpu - before.cpu
+		sample.ReadBytes += usage.readBytes - before.readBytes
+		sample.WriteBytes += usage.writeBytes - before.writeBytes
+	}
+
+	return sample, cur
+}
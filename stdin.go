@@ -0,0 +1,190 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StdinSource represents where StdIn of process comes from, if executor does not write it
+type StdinSource int
+
+const (
+	StdinInherit StdinSource = iota // StdIn of this process, shared with other processes inheriting it
+	StdinNull                       // Null device, giving EOF at once, for daemons and concurrent processes
+	StdinCustom                     // Reader of Options.Stdin
+)
+
+// StdinClosing represents when StdIn written by executor is closed, signaling EOF to the process
+type StdinClosing int
+
+const (
+	StdinCloseOnEOF    StdinClosing = iota // Close once Options.StdinFunc returns error, like io.EOF
+	StdinKeepOpen                          // Keep open until the process is waited for, for programs exiting on EOF too early
+	StdinCloseOnSignal                     // Close once Options.StdinCloseSignal is closed or receives a value, or the process is waited for
+)
+
+// stdinWriter writes into StdIn of process, counting bytes written
+type stdinWriter struct {
+	mu sync.Mutex // Serializes writes from several sources
+	w  io.WriteCloser
+	n  int64
+
+	// Pacing of fed data
+	rate      int64         // Bytes per second
+	lineDelay time.Duration // Delay after each line
+	start     time.Time
+	paced     int64 // Bytes written with rate limit since start
+
+	encode  encodeFunc // Converter of written UTF-8 text into encoding of StdIn, if any
+	pending []byte     // Incomplete trailing character of the last write, to encode with the next one
+
+	closing StdinClosing
+}
+
+// newStdinWriter returns writer into StdIn pipe <w>
+func newStdinWriter(w io.WriteCloser) *stdinWriter {
+	return &stdinWriter{w: w}
+}
+
+// write writes <p> into StdIn
+func (s *stdinWriter) write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.encode != nil {
+		p = append(s.pending, p...)
+		complete := completeRunes(p)
+		s.pending = append([]byte(nil), p[complete:]...)
+		p = s.encode(p[:complete])
+	}
+	n, err := s.w.Write(p)
+	atomic.AddInt64(&s.n, int64(n))
+	return err
+}
+
+// close closes StdIn, signaling EOF to the process
+func (s *stdinWriter) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.w.Close()
+}
+
+// closeOn closes StdIn once <signal> is closed or receives a value, unless <stop> is closed first
+func (s *stdinWriter) closeOn(signal <-chan struct{}, stop <-chan struct{}) {
+	select {
+	case <-signal:
+		s.close()
+	case <-stop:
+	}
+}
+
+// feed writes data produced by <next> until it returns error, then closes StdIn if closing policy says so.
+// io.EOF from <next> means there is no more data
+func (s *stdinWriter) feed(next func() ([]byte, error)) {
+	defer func() {
+		if s.closing == StdinCloseOnEOF {
+			s.close()
+		}
+	}()
+	for {
+		data, err := next()
+		if len(data) > 0 {
+			// Process closed its StdIn or exited
+			if err := s.pacedWrite(data); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				diagln(err)
+			}
+			return
+		}
+	}
+}
+
+// pacedWrite writes <p> into StdIn, delaying after each line and keeping the byte rate as configured
+func (s *stdinWriter) pacedWrite(p []byte) error {
+	if s.lineDelay <= 0 {
+		return s.rateWrite(p)
+	}
+	for len(p) > 0 {
+		line := p
+		if idx := bytes.IndexByte(p, '\n'); idx >= 0 {
+			line = p[:idx+1]
+		}
+		if err := s.rateWrite(line); err != nil {
+			return err
+		}
+		p = p[len(line):]
+		if line[len(line)-1] == '\n' {
+			time.Sleep(s.lineDelay)
+		}
+	}
+	return nil
+}
+
+// rateWrite writes <p> into StdIn in small chunks, sleeping between them to not exceed the byte rate
+func (s *stdinWriter) rateWrite(p []byte) error {
+	if s.rate <= 0 {
+		return s.write(p)
+	}
+	if s.start.IsZero() {
+		s.start = time.Now()
+	}
+
+	// Write up to 10 chunks per second
+	chunkSize := int(s.rate / 10)
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		if err := s.write(chunk); err != nil {
+			return err
+		}
+		p = p[len(chunk):]
+
+		s.paced += int64(len(chunk))
+		expected := time.Duration(s.paced) * time.Second / time.Duration(s.rate)
+		if elapsed := time.Since(s.start); elapsed < expected {
+			time.Sleep(expected - elapsed)
+		}
+	}
+	return nil
+}
+
+// bytesWritten returns number of bytes written into StdIn. Safe to call on nil writer
+func (s *stdinWriter) bytesWritten() int64 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&s.n)
+}
+
+// inputPipe returns pipe connected to StdIn of <cmd>.
+// If <own> is set, the pipe is created by executor instead of <cmd>, and its read end is returned to close once the
+// process is started
+func inputPipe(cmd *exec.Cmd, own bool) (*trackedPipe, *trackedPipe, error) {
+	if !own {
+		w, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		return trackPipe(w), nil, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.Stdin = r
+	return trackPipe(w), trackPipe(r), nil
+}
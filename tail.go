@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// defaultStderrTailSize is the number of StdErr bytes kept in Result.StderrTail if Options.StderrTailSize is not set
+const defaultStderrTailSize = 4096
+
+// tailBuffer is a writer keeping only the last <size> bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+// newTailBuffer returns tailBuffer keeping <size> bytes
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+// Write implements io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(p)
+	if n >= t.size {
+		t.buf = append(t.buf[:0], p[n-t.size:]...)
+		return n, nil
+	}
+	if over := len(t.buf) + n - t.size; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	t.buf = append(t.buf, p...)
+	return n, nil
+}
+
+// String returns kept bytes, without a character cut in half at the beginning
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.buf
+	for i := 0; i < len(b) && i < utf8.UTFMax && !utf8.RuneStart(b[i]); i++ {
+		b = b[1:]
+	}
+	return string(b)
+}
+
+// ExitError represents a process which did not finish successfully, with the end of its StdErr
+type ExitError struct {
+	Command   string    // Command that was run
+	ExitCode  int       // Exit code
+	EndReason EndReason // Why execution ended
+	Stderr    string    // Last bytes of StdErr, see Options.StderrTailSize
+}
+
+// newExitError returns ExitError for <command> which produced <res>, or nil if it finished successfully
+func newExitError(command string, res Result) error {
+	if res.DoneOk {
+		return nil
+	}
+	return &ExitError{Command: command, ExitCode: res.ExitCode, EndReason: res.EndReason, Stderr: res.StderrTail}
+}
+
+// Error implements error
+func (e *ExitError) Error() string {
+	var msg string
+	switch e.EndReason {
+	case EndExited:
+		msg = fmt.Sprintf("%v exited with code %v", e.Command, e.ExitCode)
+	case EndNone:
+		msg = fmt.Sprintf("%v did not finish", e.Command)
+	default:
+		msg = fmt.Sprintf("%v ended by %v", e.Command, e.EndReason)
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// Unwrap returns error describing EndReason, to use with errors.Is
+func (e *ExitError) Unwrap() error {
+	switch e.EndReason {
+	case EndTimeout:
+		return ErrTimeout
+	case EndCanceled:
+		return ErrCanceled
+	case EndMemoryLimit:
+		return ErrMemoryLimit
+	case EndCPUTimeLimit:
+		return ErrCPUTimeLimit
+	default:
+		return ErrNonZeroExit
+	}
+}
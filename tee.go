@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// pipeTee reads StdOut of process, writing all of it into the pipe to the next process of Pipeline and returning
+// the first <limit> bytes to the output scanner. Data is written to the next process before it is returned,
+// so the captured copy has the same order and never gets ahead of what the next process received
+type pipeTee struct {
+	src       io.ReadCloser
+	out       *os.File
+	limit     int64 // Bytes to return (0 = all)
+	returned  int64
+	outFailed bool // Next process does not read anymore
+	closeOnce sync.Once
+}
+
+// newPipeTee returns tee of <src> into <out>, returning <limit> bytes (0 = all)
+func newPipeTee(src io.ReadCloser, out *os.File, limit int64) *pipeTee {
+	return &pipeTee{src: src, out: out, limit: limit}
+}
+
+// Read implements io.Reader
+func (t *pipeTee) Read(p []byte) (int, error) {
+	if t.limit > 0 && t.returned >= t.limit {
+		// Rest of data is only forwarded, then the scanner gets EOF
+		t.forward()
+		t.closeOut()
+		return 0, io.EOF
+	}
+	if t.limit > 0 && int64(len(p)) > t.limit-t.returned {
+		p = p[:t.limit-t.returned]
+	}
+
+	n, err := t.src.Read(p)
+	if n > 0 {
+		t.write(p[:n])
+		t.returned += int64(n)
+	}
+	if err != nil {
+		// Next process gets EOF once this one closed its StdOut
+		t.closeOut()
+		if t.outFailed {
+			err = io.EOF
+		}
+	}
+	return n, err
+}
+
+// write writes <p> into the next process. Once it stops reading, StdOut is closed, so this process gets SIGPIPE or
+// write error, like shells' tee does
+func (t *pipeTee) write(p []byte) {
+	if t.outFailed {
+		return
+	}
+	if _, err := t.out.Write(p); err != nil {
+		t.outFailed = true
+		_ = t.src.Close()
+	}
+}
+
+// forward copies the rest of data into the next process
+func (t *pipeTee) forward() {
+	buf := scanBufferPool.Get().(*[]byte)
+	defer scanBufferPool.Put(buf)
+	for {
+		n, err := t.src.Read(*buf)
+		if n > 0 {
+			t.write((*buf)[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// closeOut closes the pipe to the next process. Safe to call more than once
+func (t *pipeTee) closeOut() {
+	t.closeOnce.Do(func() {
+		if t.out.Close() == nil {
+			pipesOpened(-1)
+		}
+	})
+}
+
+// Close implements io.Closer
+func (t *pipeTee) Close() error {
+	t.closeOut()
+	return t.src.Close()
+}
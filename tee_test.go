@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+	"testing/iotest"
+)
+
+// teeOutput returns numbered lines of output, so misplaced or lost parts are noticed
+func teeOutput(lines int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&buf, "line %06d of piped output\n", i)
+	}
+	return buf.Bytes()
+}
+
+// runTee reads <src> through tee with <limit> into pipe, returning the data returned by tee and received from pipe
+func runTee(t testing.TB, src io.ReadCloser, limit int64) ([]byte, []byte) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	received := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		received <- data
+	}()
+
+	tee := newPipeTee(src, w, limit)
+	returned, err := io.ReadAll(tee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = tee.Close()
+	return returned, <-received
+}
+
+func TestPipeTeeOrderAndCompleteness(t *testing.T) {
+	data := teeOutput(10000)
+	// Short reads of varying size
+	src := io.NopCloser(iotest.HalfReader(bytes.NewReader(data)))
+	returned, received := runTee(t, src, 0)
+	if !bytes.Equal(returned, data) {
+		t.Errorf("captured %v bytes differ from %v bytes of output", len(returned), len(data))
+	}
+	if !bytes.Equal(received, data) {
+		t.Errorf("next process received %v bytes differing from %v bytes of output", len(received), len(data))
+	}
+}
+
+func TestPipeTeeLimit(t *testing.T) {
+	data := teeOutput(10000)
+	for _, limit := range []int64{1, 100, 4096, int64(len(data)) - 1, int64(len(data)), int64(len(data)) + 1} {
+		src := io.NopCloser(iotest.HalfReader(bytes.NewReader(data)))
+		returned, received := runTee(t, src, limit)
+		want := data
+		if limit < int64(len(data)) {
+			want = data[:limit]
+		}
+		if !bytes.Equal(returned, want) {
+			t.Errorf("limit %v: captured %v bytes, want the first %v bytes of output", limit, len(returned), len(want))
+		}
+		if !bytes.Equal(received, data) {
+			t.Errorf("limit %v: next process received %v bytes, want all %v", limit, len(received), len(data))
+		}
+	}
+}
+
+func TestPipeTeeNextProcessGone(t *testing.T) {
+	srcR, srcW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcW.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+
+	// Writer is told the output is not read anymore, like with SIGPIPE
+	written := make(chan error, 1)
+	go func() {
+		data := teeOutput(1000)
+		for {
+			if _, err := srcW.Write(data); err != nil {
+				written <- err
+				return
+			}
+		}
+	}()
+	tee := newPipeTee(srcR, w, 0)
+	if _, err := io.ReadAll(tee); err != nil {
+		t.Errorf("reading tee failed: %v", err)
+	}
+	if err := <-written; err == nil {
+		t.Error("writer did not get error")
+	}
+	_ = tee.Close()
+}
+
+func TestPipelineTeePiped(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell is not available on Windows")
+	}
+	results := Pipeline{Commands: []Options{
+		{Command: "/bin/sh", Args: []string{"-c", "i=0; while [ $i -lt 2000 ]; do echo line $i; i=$((i+1)); done"}, Capture: true, TeePiped: true},
+		{Command: "/bin/sh", Args: []string{"-c", "cat"}, Capture: true},
+	}}.Run()
+	for i, res := range results {
+		if !res.DoneOk {
+			t.Fatalf("process %v failed: %+v", i, res)
+		}
+	}
+	if results[0].Output == "" || results[0].Output != results[1].Output {
+		t.Errorf("captured %v bytes, next process printed %v bytes", len(results[0].Output), len(results[1].Output))
+	}
+}
+
+func BenchmarkPipeTee(b *testing.B) {
+	data := teeOutput(10000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runTee(b, io.NopCloser(bytes.NewReader(data)), 0)
+	}
+}
+
+func BenchmarkPipeTeeLimit(b *testing.B) {
+	data := teeOutput(10000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runTee(b, io.NopCloser(bytes.NewReader(data)), 4096)
+	}
+}
@@ -0,0 +1,13 @@
+// +build !windows
+
+package executor
+
+import (
+	"os"
+)
+
+// isTerminal returns true if <f> is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
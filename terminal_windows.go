@@ -0,0 +1,15 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// isTerminal returns true if <f> is a console
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(f.Fd()), &mode) == nil
+}
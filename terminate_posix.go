@@ -0,0 +1,14 @@
+// +build !windows
+
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// terminate asks <proc> to exit with SIGTERM. Returns false if it can't be asked, so it should be killed instead
+func terminate(proc *os.Process) bool {
+	err := proc.Signal(syscall.SIGTERM)
+	return err == nil || err == os.ErrProcessDone
+}
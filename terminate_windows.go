@@ -0,0 +1,13 @@
+// +build windows
+
+package executor
+
+import (
+	"os"
+)
+
+// terminate returns false, as there is no signal to ask process without console window to exit, so it should be
+// killed instead
+func terminate(_ *os.Process) bool {
+	return false
+}
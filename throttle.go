@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"io"
+	"time"
+)
+
+// throttledReader limits the rate of reading from the underlying reader.
+// Not reading fast enough makes the pipe fill up, so the writing process blocks instead of executor buffering data
+type throttledReader struct {
+	r     io.Reader
+	rate  int64 // Bytes per second
+	start time.Time
+	total int64
+}
+
+// newThrottledReader returns reader reading from <r> at most <rate> bytes per second
+func newThrottledReader(r io.Reader, rate int64) *throttledReader {
+	return &throttledReader{r: r, rate: rate}
+}
+
+// Read implements io.Reader
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+	// Don't read more than a second worth of data at once
+	if int64(len(p)) > t.rate {
+		p = p[:t.rate]
+	}
+
+	n, err := t.r.Read(p)
+	t.total += int64(n)
+
+	expected := time.Duration(t.total) * time.Second / time.Duration(t.rate)
+	if elapsed := time.Since(t.start); elapsed < expected {
+		time.Sleep(expected - elapsed)
+	}
+
+	return n, err
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces values of sensitive environment variables in ExecTrace
+const redactedValue = "[REDACTED]"
+
+// sensitiveEnvNames are case-insensitive parts of environment variable names, values of which are redacted in ExecTrace
+var sensitiveEnvNames = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL", "AUTH", "COOKIE", "SESSION"}
+
+// ExecTrace represents an auditable record of what was executed
+type ExecTrace struct {
+	Path      string    // Resolved path of executable
+	Args      []string  // Final command line, starting with the command itself
+	Env       []string  // Environment in "KEY=VALUE" form, with values of sensitive variables redacted
+	Dir       string    // Absolute working directory
+	UID       int       // User ID of the parent process (-1 on Windows)
+	User      string    // User name of the parent process
+	StartTime time.Time // Time the process was started at
+	Host      string    // Host name
+}
+
+// newExecTrace returns trace of <cmd> about to be started. Variables with names containing any of <redact>
+// are redacted in addition to common sensitive ones
+func newExecTrace(cmd *exec.Cmd, redact []string) ExecTrace {
+	trace := ExecTrace{
+		Path:      cmd.Path,
+		Args:      append([]string(nil), cmd.Args...),
+		UID:       os.Getuid(),
+		StartTime: time.Now(),
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	trace.Env = redactEnv(env, append(sensitiveEnvNames, redact...))
+
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	trace.Dir = dir
+
+	if u, err := user.Current(); err == nil {
+		trace.User = u.Username
+	}
+	trace.Host, _ = os.Hostname()
+
+	return trace
+}
+
+// redactEnv returns copy of <env> with values of variables, names of which contain any of <names>, redacted
+func redactEnv(env []string, names []string) []string {
+	redacted := make([]string, 0, len(env))
+	for _, kv := range env {
+		idx := strings.Index(kv, "=")
+		if idx < 0 {
+			redacted = append(redacted, kv)
+			continue
+		}
+		upperKey := strings.ToUpper(kv[:idx])
+		for _, name := range names {
+			if strings.Contains(upperKey, strings.ToUpper(name)) {
+				kv = kv[:idx+1] + redactedValue
+				break
+			}
+		}
+		redacted = append(redacted, kv)
+	}
+	return redacted
+}
@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"io"
+)
+
+// Transform wraps reader of output stream into reader of transformed output, like StripANSI does.
+// Transforms are applied to StdOut and StdErr separately, each time with a new reader
+type Transform func(r io.Reader) io.Reader
+
+// filterFunc returns transformed <p>. It is called with <eof> set once when there is no more data
+type filterFunc func(p []byte, eof bool) []byte
+
+// filterReader transforms data from the underlying reader with stateful filter function
+type filterReader struct {
+	r      io.Reader
+	filter filterFunc
+	buf    []byte
+	out    []byte
+	err    error
+}
+
+// newFilterReader returns reader filtering data from <r> with <filter>
+func newFilterReader(r io.Reader, filter filterFunc) *filterReader {
+	return &filterReader{r: r, filter: filter, buf: make([]byte, 4096)}
+}
+
+// Read implements io.Reader
+func (f *filterReader) Read(p []byte) (int, error) {
+	for len(f.out) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		n, err := f.r.Read(f.buf)
+		f.err = err
+		f.out = append(f.out[:0], f.filter(f.buf[:n], err != nil)...)
+	}
+	n := copy(p, f.out)
+	f.out = f.out[n:]
+	return n, nil
+}
+
+// DecodeCodePage returns transform decoding output in code page <cp> into UTF-8 (code pages other than UTF-16 are supported on Windows only)
+func DecodeCodePage(cp CodePage) Transform {
+	return func(r io.Reader) io.Reader {
+		if cp == CodePageUTF8 {
+			return r
+		}
+		decode, err := newDecoder(cp)
+		if err != nil {
+			diagln(err)
+			return r
+		}
+		return newDecodingReader(r, decode)
+	}
+}
+
+// StripBOM removes UTF-8 byte order mark from the beginning of output
+func StripBOM(r io.Reader) io.Reader {
+	bom := []byte("\xef\xbb\xbf")
+	matched := 0
+	done := false
+	return newFilterReader(r, func(p []byte, eof bool) []byte {
+		if done {
+			return p
+		}
+		var out []byte
+		for i, b := range p {
+			if b == bom[matched] {
+				matched++
+				if matched == len(bom) {
+					done = true
+					return append(out, p[i+1:]...)
+				}
+				continue
+			}
+			// Not a BOM: return bytes held back so far
+			done = true
+			out = append(out, bom[:matched]...)
+			return append(out, p[i:]...)
+		}
+		if eof {
+			done = true
+			return append(out, bom[:matched]...)
+		}
+		return out
+	})
+}
+
+// NormalizeNewlines converts CRLF and CR line endings of output into LF
+func NormalizeNewlines(r io.Reader) io.Reader {
+	pendingCR := false
+	return newFilterReader(r, func(p []byte, eof bool) []byte {
+		out := make([]byte, 0, len(p)+1)
+		for _, b := range p {
+			if pendingCR {
+				pendingCR = false
+				out = append(out, '\n')
+				if b == '\n' {
+					continue
+				}
+			}
+			if b == '\r' {
+				pendingCR = true
+				continue
+			}
+			out = append(out, b)
+		}
+		if eof && pendingCR {
+			pendingCR = false
+			out = append(out, '\n')
+		}
+		return out
+	})
+}
+
+// ANSI escape sequence parser states
+const (
+	ansiText   = iota // Outside of escape sequence
+	ansiEscape        // After ESC
+	ansiCSI           // Inside control sequence, after ESC [
+	ansiOSC           // Inside operating system command, after ESC ]
+	ansiOSCEsc        // After ESC inside operating system command
+)
+
+// StripANSI removes ANSI escape sequences (colors, cursor movement, window titles) from output
+func StripANSI(r io.Reader) io.Reader {
+	state := ansiText
+	return newFilterReader(r, func(p []byte, eof bool) []byte {
+		out := make([]byte, 0, len(p))
+		for _, b := range p {
+			switch state {
+			case ansiText:
+				if b == 0x1b {
+					state = ansiEscape
+				} else {
+					out = append(out, b)
+				}
+			case ansiEscape:
+				switch b {
+				case '[':
+					state = ansiCSI
+				case ']':
+					state = ansiOSC
+				default:
+					// Two-byte sequence
+					state = ansiText
+				}
+			case ansiCSI:
+				if b >= 0x40 && b <= 0x7e {
+					state = ansiText
+				}
+			case ansiOSC:
+				if b == 0x07 {
+					state = ansiText
+				} else if b == 0x1b {
+					state = ansiOSCEsc
+				}
+			case ansiOSCEsc:
+				if b == '\\' {
+					state = ansiText
+				} else {
+					state = ansiOSC
+				}
+			}
+		}
+		return out
+	})
+}
@@ -0,0 +1,22 @@
+// +build !windows
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// errNoUserSessions is returned as sessions of logged on users are a Windows feature
+var errNoUserSessions = errors.New("running in user session is only supported on Windows")
+
+// startInUserSession returns error as sessions of logged on users are a Windows feature
+func startInUserSession(ctx context.Context, cmd *exec.Cmd) error {
+	return errNoUserSessions
+}
+
+// userSessionEnviron returns error as sessions of logged on users are a Windows feature
+func userSessionEnviron() ([]string, error) {
+	return nil, errNoUserSessions
+}
@@ -0,0 +1,287 @@
+// +build windows
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// noActiveSession is returned by WTSGetActiveConsoleSessionId if no session is attached to the console
+const noActiveSession = 0xFFFFFFFF
+
+// userDesktop is the interactive desktop of user session, without it programs loading user32.dll fail to initialize
+const userDesktop = `winsta0\default`
+
+var (
+	procWTSGetActiveConsoleSessionId = windows.NewLazySystemDLL("kernel32.dll").NewProc("WTSGetActiveConsoleSessionId")
+	procCreateProcessAsUserW         = windows.NewLazySystemDLL("advapi32.dll").NewProc("CreateProcessAsUserW")
+)
+
+// startInUserSession starts <cmd> like cmd.Start, but in session of logged on user with their token, like from a
+// service running as SYSTEM (SeTcbPrivilege is required). Session with user at the console is preferred, then any
+// active remote one. Process gets the interactive desktop, and environment of the user unless <cmd> has its own.
+// Standard streams of <cmd> must be files or nil.
+//
+// cmd.Process is set, so cmd.Wait can be used. Process is killed once <ctx> is done, like with exec.CommandContext
+func startInUserSession(ctx context.Context, cmd *exec.Cmd) error {
+	token, err := userSessionToken()
+	if err != nil {
+		return err
+	}
+	defer token.Close()
+
+	env := cmd.Env
+	if env == nil {
+		if env, err = token.Environ(false); err != nil {
+			return err
+		}
+	}
+	envBlock, err := createEnvBlock(dedupEnv(env))
+	if err != nil {
+		return err
+	}
+
+	// Handles are inherited from the parent, so they are duplicated as inheritable and listed explicitly to not leak
+	// other ones inheritable at the moment
+	stdio, closeStdio, err := stdioHandles(cmd)
+	defer closeStdio()
+	if err != nil {
+		return err
+	}
+	attrs, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return err
+	}
+	defer attrs.Delete()
+	err = attrs.Update(windows.PROC_THREAD_ATTRIBUTE_HANDLE_LIST, 0, unsafe.Pointer(&stdio[0]),
+		uintptr(len(stdio))*unsafe.Sizeof(stdio[0]), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	desktop, err := windows.UTF16PtrFromString(userDesktop)
+	if err != nil {
+		return err
+	}
+	si := &windows.StartupInfoEx{
+		StartupInfo: windows.StartupInfo{
+			Desktop:   desktop,
+			Flags:     windows.STARTF_USESTDHANDLES,
+			StdInput:  stdio[0],
+			StdOutput: stdio[1],
+			StdErr:    stdio[2],
+		},
+		ProcThreadAttributeList: attrs,
+	}
+	si.Cb = uint32(unsafe.Sizeof(*si))
+	flags := uint32(windows.CREATE_UNICODE_ENVIRONMENT | windows.EXTENDED_STARTUPINFO_PRESENT)
+	if attr := cmd.SysProcAttr; attr != nil {
+		flags |= attr.CreationFlags
+		if attr.HideWindow {
+			si.Flags |= windows.STARTF_USESHOWWINDOW
+			si.ShowWindow = windows.SW_HIDE
+		}
+	}
+
+	appName, err := windows.UTF16PtrFromString(cmd.Path)
+	if err != nil {
+		return err
+	}
+	cmdLine, err := windows.UTF16PtrFromString(commandLine(cmd))
+	if err != nil {
+		return err
+	}
+	var dir *uint16
+	if cmd.Dir != "" {
+		if dir, err = windows.UTF16PtrFromString(cmd.Dir); err != nil {
+			return err
+		}
+	}
+
+	var pi windows.ProcessInformation
+	r, _, err := procCreateProcessAsUserW.Call(
+		uintptr(token),
+		uintptr(unsafe.Pointer(appName)),
+		uintptr(unsafe.Pointer(cmdLine)),
+		0,
+		0,
+		1,
+		uintptr(flags),
+		uintptr(unsafe.Pointer(&envBlock[0])),
+		uintptr(unsafe.Pointer(dir)),
+		uintptr(unsafe.Pointer(si)),
+		uintptr(unsafe.Pointer(&pi)),
+	)
+	if r == 0 {
+		return os.NewSyscallError("CreateProcessAsUser", err)
+	}
+	_ = windows.CloseHandle(pi.Thread)
+
+	// Process handle is held until os.Process is opened, so the ID is not reused meanwhile
+	proc, err := os.FindProcess(int(pi.ProcessId))
+	if err != nil {
+		_ = windows.TerminateProcess(pi.Process, 1)
+		_ = windows.CloseHandle(pi.Process)
+		return err
+	}
+	cmd.Process = proc
+	watchUserSessionProcess(ctx, pi.Process)
+	return nil
+}
+
+// watchUserSessionProcess kills process with <handle> once <ctx> is done, until it exits, then closes the handle
+func watchUserSessionProcess(ctx context.Context, handle windows.Handle) {
+	done := ctx.Done()
+	if done == nil {
+		_ = windows.CloseHandle(handle)
+		return
+	}
+	exited := make(chan struct{})
+	goTracked(func() {
+		_, _ = windows.WaitForSingleObject(handle, windows.INFINITE)
+		close(exited)
+	})
+	goTracked(func() {
+		select {
+		case <-done:
+			_ = windows.TerminateProcess(handle, 1)
+			<-exited
+		case <-exited:
+		}
+		_ = windows.CloseHandle(handle)
+	})
+}
+
+// stdioHandles returns inheritable duplicates of handles of StdIn, StdOut and StdErr of <cmd>, using the null device
+// for nil streams. Returned function closes the duplicates
+func stdioHandles(cmd *exec.Cmd) ([]windows.Handle, func(), error) {
+	var handles []windows.Handle
+	var files []*os.File
+	closeAll := func() {
+		for _, h := range handles {
+			_ = windows.CloseHandle(h)
+		}
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+
+	self := windows.CurrentProcess()
+	for i, stream := range []interface{}{cmd.Stdin, cmd.Stdout, cmd.Stderr} {
+		var f *os.File
+		switch s := stream.(type) {
+		case nil:
+		case *os.File:
+			f = s
+		default:
+			return nil, closeAll, fmt.Errorf("%w: stream %v of type %T with Options.UserSession, only files can be given to process", ErrUnsupported, i, stream)
+		}
+		if f == nil {
+			// Standard streams of service may be not set at all
+			flag := os.O_WRONLY
+			if i == 0 {
+				flag = os.O_RDONLY
+			}
+			null, err := os.OpenFile(os.DevNull, flag, 0)
+			if err != nil {
+				return nil, closeAll, err
+			}
+			files = append(files, null)
+			f = null
+		}
+		var h windows.Handle
+		err := windows.DuplicateHandle(self, windows.Handle(f.Fd()), self, &h, 0, true, windows.DUPLICATE_SAME_ACCESS)
+		if err != nil {
+			return nil, closeAll, err
+		}
+		handles = append(handles, h)
+	}
+	return handles, closeAll, nil
+}
+
+// commandLine returns command line of <cmd>, as built by exec.Cmd
+func commandLine(cmd *exec.Cmd) string {
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.CmdLine != "" {
+		return cmd.SysProcAttr.CmdLine
+	}
+	args := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = syscall.EscapeArg(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// createEnvBlock returns environment block of <env> for CreateProcess with CREATE_UNICODE_ENVIRONMENT
+func createEnvBlock(env []string) ([]uint16, error) {
+	var block []uint16
+	for _, pair := range env {
+		s, err := windows.UTF16FromString(pair)
+		if err != nil {
+			return nil, err
+		}
+		block = append(block, s...)
+	}
+	// Empty block is terminated with two NULs too
+	if len(block) == 0 {
+		block = append(block, 0)
+	}
+	return append(block, 0), nil
+}
+
+// userSessionEnviron returns environment of the logged on user, see startInUserSession
+func userSessionEnviron() ([]string, error) {
+	token, err := userSessionToken()
+	if err != nil {
+		return nil, err
+	}
+	defer token.Close()
+	return token.Environ(false)
+}
+
+// userSessionToken returns token of the logged on user, see startInUserSession
+func userSessionToken() (windows.Token, error) {
+	session, err := activeUserSession()
+	if err != nil {
+		return 0, err
+	}
+	var token windows.Token
+	if err := windows.WTSQueryUserToken(session, &token); err != nil {
+		return 0, err
+	}
+	return token, nil
+}
+
+// activeUserSession returns ID of session attached to the console, or of the first active remote session
+func activeUserSession() (uint32, error) {
+	r, _, _ := procWTSGetActiveConsoleSessionId.Call()
+	if session := uint32(r); session != noActiveSession {
+		var token windows.Token
+		// Console session may have no user logged on, like at the logon screen
+		if err := windows.WTSQueryUserToken(session, &token); err == nil {
+			token.Close()
+			return session, nil
+		}
+	}
+
+	var sessions *windows.WTS_SESSION_INFO
+	var count uint32
+	if err := windows.WTSEnumerateSessions(0, 0, 1, &sessions, &count); err != nil {
+		return 0, err
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(sessions)))
+	for _, s := range (*[1 << 20]windows.WTS_SESSION_INFO)(unsafe.Pointer(sessions))[:count:count] {
+		if s.State == windows.WTSActive {
+			return s.SessionID, nil
+		}
+	}
+	return 0, errors.New("no user is logged on")
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Verbosity represents how much the package prints
+type Verbosity int32
+
+const (
+	VerbositySilent Verbosity = iota // Print nothing, neither output of processes nor errors
+	VerbosityErrors                  // Print errors only, output of processes is not printed even if Options.Print is set
+	VerbosityNormal                  // Print errors and output of processes with Options.Print set
+	VerbosityDebug                   // Additionally print command line and environment of each started process
+)
+
+// verbosity is the current Verbosity
+var verbosity = int32(VerbosityNormal)
+
+// SetVerbosity sets how much the package prints for all processes
+func SetVerbosity(v Verbosity) {
+	atomic.StoreInt32(&verbosity, int32(v))
+}
+
+// GetVerbosity returns the current Verbosity
+func GetVerbosity() Verbosity {
+	return Verbosity(atomic.LoadInt32(&verbosity))
+}
+
+// diagln prints diagnostic message of <a> to StdErr, if verbosity allows errors
+func diagln(a ...interface{}) {
+	if GetVerbosity() >= VerbosityErrors {
+		fmt.Fprintln(os.Stderr, a...)
+	}
+}
+
+// Diagln prints diagnostic message of <a> to StdErr, if verbosity allows errors. For backends outside of this package
+func Diagln(a ...interface{}) {
+	diagln(a...)
+}
+
+// diagf prints diagnostic message formatted with <format> to StdErr, if verbosity allows errors
+func diagf(format string, a ...interface{}) {
+	if GetVerbosity() >= VerbosityErrors {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
+
+// debugTrace prints command line and environment of <trace> to StdErr, if verbosity is VerbosityDebug
+func debugTrace(trace ExecTrace) {
+	if GetVerbosity() < VerbosityDebug {
+		return
+	}
+	quoted := make([]string, 0, len(trace.Args))
+	for _, arg := range trace.Args {
+		quoted = append(quoted, QuotePosix(arg))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "+ %v\n", strings.Join(quoted, " "))
+	fmt.Fprintf(&sb, "  dir: %v\n", trace.Dir)
+	for _, kv := range trace.Env {
+		fmt.Fprintf(&sb, "  env: %v\n", kv)
+	}
+	fmt.Fprint(os.Stderr, sb.String())
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// verifyExecutable resolves Options.Command started in <dir> and checks it has Options.ExpectedSHA256 hash, valid
+// code signature if Options.RequireSignature is set and passes Options.Verifier
+func verifyExecutable(opts Options, dir string) error {
+	expectedSHA256, verifier := opts.ExpectedSHA256, opts.Verifier
+	if expectedSHA256 == "" && verifier == nil && !opts.RequireSignature {
+		return nil
+	}
+
+	path, err := resolveCommand(opts.Command, dir)
+	if err != nil {
+		return err
+	}
+
+	if expectedSHA256 != "" {
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(hash, expectedSHA256) {
+			return fmt.Errorf("%w: %v has SHA-256 %v, expected %v", ErrChecksumMismatch, path, hash, expectedSHA256)
+		}
+	}
+
+	if opts.RequireSignature {
+		if err := verifySignature(path, opts.SignerSubject); err != nil {
+			return err
+		}
+	}
+
+	if verifier != nil {
+		return verifier(path)
+	}
+	return nil
+}
+
+// resolveCommand returns path of executable <command> started in <dir>, the way exec.Cmd finds it: names without
+// path separators are looked up in PATH, other relative paths are relative to <dir>
+func resolveCommand(command string, dir string) (string, error) {
+	if filepath.Base(command) != command && !filepath.IsAbs(command) && dir != "" {
+		// Absolute, so joined path is never taken for a name to look up in PATH
+		path, err := filepath.Abs(filepath.Join(dir, command))
+		if err != nil {
+			return "", err
+		}
+		command = path
+	}
+	return exec.LookPath(command)
+}
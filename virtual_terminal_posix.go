@@ -0,0 +1,6 @@
+// +build !windows
+
+package executor
+
+// enableVirtualTerminal does nothing, as terminals interpret ANSI escape sequences on this platform
+func enableVirtualTerminal() {}
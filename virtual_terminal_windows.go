@@ -0,0 +1,28 @@
+// +build windows
+
+package executor
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+var virtualTerminalOnce sync.Once
+
+// enableVirtualTerminal makes console of the current process interpret ANSI escape sequences, used for colors
+func enableVirtualTerminal() {
+	virtualTerminalOnce.Do(func() {
+		for _, std := range []uint32{windows.STD_OUTPUT_HANDLE, windows.STD_ERROR_HANDLE} {
+			handle, err := windows.GetStdHandle(std)
+			if err != nil {
+				continue
+			}
+			var mode uint32
+			if err := windows.GetConsoleMode(handle, &mode); err != nil {
+				continue
+			}
+			_ = windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+		}
+	})
+}
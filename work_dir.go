@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DatedDir returns function for Options.DirFunc making path of directory inside <base>, named after the current
+// time formatted with <layout>, like "2006-01-02_15-04-05". Use with Options.CreateDir to get fresh directory each run
+func DatedDir(base string, layout string) func() string {
+	return func() string {
+		return filepath.Join(base, time.Now().Format(layout))
+	}
+}
+
+// prepareDir returns working directory for the process according to <opts>.
+// Returned cleanup function removes temporary directory, if it was created
+func prepareDir(opts Options) (string, func(), error) {
+	noop := func() {}
+
+	if opts.DirFunc != nil {
+		opts.Dir = opts.DirFunc()
+	}
+
+	if opts.CreateDir && opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return "", noop, err
+		}
+	}
+
+	if opts.TempDir {
+		dir, err := os.MkdirTemp(opts.Dir, "executor-")
+		if err != nil {
+			return "", noop, err
+		}
+		return dir, func() { os.RemoveAll(dir) }, nil
+	}
+
+	return opts.Dir, noop, nil
+}
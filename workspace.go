@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Workspace represents a scratch directory with staged input files and collected output files
+type Workspace struct {
+	Inputs       map[string]string // Files to copy into workspace before start (relative path in workspace -> source path)
+	Templates    map[string]string // Template files to render into workspace before start (relative path in workspace -> template path)
+	TemplateData interface{}       // Data to render templates with
+	Outputs      []string          // Glob patterns (relative to workspace) of files to collect after process finished
+	ResultsDir   string            // Directory to copy collected files into, keeping their relative paths
+}
+
+// Run stages inputs into a new temporary directory, runs a process described by <opts> in it,
+// collects outputs into ResultsDir and removes the directory.
+//
+// Options.Dir (or Options.DirFunc) is used as a parent of the workspace directory. Process is always waited for.
+// Returns paths of collected files
+func (w Workspace) Run(opts Options) (Result, []string, error) {
+	if opts.DirFunc != nil {
+		opts.Dir = opts.DirFunc()
+		opts.DirFunc = nil
+	}
+	dir, err := os.MkdirTemp(opts.Dir, "executor-workspace-")
+	if err != nil {
+		return Result{ExitCode: -1}, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := w.stage(dir); err != nil {
+		return Result{ExitCode: -1}, nil, err
+	}
+
+	opts.Dir = dir
+	opts.Wait = true
+	opts.CreateDir = false
+	opts.TempDir = false
+	res := Start(opts)
+	if !res.StartOk {
+		return res, nil, nil
+	}
+
+	collected, err := w.collect(dir)
+	return res, collected, err
+}
+
+// stage copies inputs and renders templates into <dir>
+func (w Workspace) stage(dir string) error {
+	for dst, src := range w.Inputs {
+		dst, err := workspacePath(dir, dst)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+
+	for dst, src := range w.Templates {
+		dst, err := workspacePath(dir, dst)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.ParseFiles(src)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		file, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		err = tmpl.Execute(file, w.TemplateData)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// workspacePath returns path of <rel> inside workspace <dir>. Absolute paths and paths leading outside of <dir>
+// are refused
+func workspacePath(dir string, rel string) (string, error) {
+	clean := filepath.Clean(rel)
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || clean == ".." ||
+		strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q leads outside of workspace", rel)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// collect copies files matching Outputs from <dir> into ResultsDir.
+// Either all files are collected or none: already copied files are removed on failure
+func (w Workspace) collect(dir string) ([]string, error) {
+	var collected []string
+	fail := func(err error) ([]string, error) {
+		for _, path := range collected {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+
+	for _, pattern := range w.Outputs {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return fail(err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return fail(err)
+			}
+			if info.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(dir, match)
+			if err != nil {
+				return fail(err)
+			}
+			dst := filepath.Join(w.ResultsDir, rel)
+			if err := copyFile(match, dst); err != nil {
+				return fail(err)
+			}
+			collected = append(collected, dst)
+		}
+	}
+	return collected, nil
+}
+
+// copyFile copies file <src> to <dst>, creating parent directories of <dst> if needed
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}